@@ -0,0 +1,381 @@
+package cache
+
+import (
+	"math/bits"
+)
+
+// An AdmissionPolicy decides whether an AdmissionCache sets a new item
+// in the cache it wraps, or rejects it so that the items already in the
+// cache are not evicted for it.
+type AdmissionPolicy[K comparable] interface {
+
+	// Record is called with the key of every request: every Get, whether
+	// it hit or missed, and every Set that does not fetch the item of a
+	// Get that just missed, which was already recorded.
+	Record(key K)
+
+	// Admit returns true if a new item with the given key and size should
+	// be set in the cache. If the cache would have to evict an item to
+	// make room for it, has_victim is true and victim is that item's key.
+	Admit(key K, size int, victim K, has_victim bool) bool
+}
+
+// A VictimCache is a Cache that can tell, without changing what it
+// holds, whether it has an item and which item it would evict next.
+// Every cache in this package is a VictimCache.
+type VictimCache[K comparable, V any] interface {
+	Cache[K, V]
+
+	// Contains returns true if an item with the key is in the cache,
+	// whether or not it has expired, without counting as a use.
+	Contains(key K) bool
+
+	// Victim returns the key of the item the cache would evict first to
+	// make room for a new item of the given size, and true, or false if
+	// the item fits without evicting anything.
+	Victim(size int) (key K, ok bool)
+}
+
+// An AdmissionCache is a Cache that asks an AdmissionPolicy whether to
+// set each new item in the Cache it wraps. Updates of items that are
+// already in a VictimCache are always set. A rejected item is not set,
+// and any older value for its key is deleted, since it is now stale.
+type AdmissionCache[K comparable, V any] struct {
+
+	// cache the items are set in
+	cache Cache[K, V]
+
+	// cache as a VictimCache, or nil if it is not one
+	victims VictimCache[K, V]
+
+	// cache as a victimPinner, or nil if it is not one
+	pinner victimPinner
+
+	// policy that admits or rejects new items
+	admission AdmissionPolicy[K]
+
+	// number of items rejected by the policy
+	rejections int
+
+	// key of the most recent Get, if it missed and no Set
+	// has been made since, so that the Set that fetches its
+	// item is not recorded as another request
+	missed_key K
+
+	// whether missed_key holds a key
+	missed bool
+}
+
+// NewAdmissionCache returns an AdmissionCache that sets the new items
+// admitted by the AdmissionPolicy in the given cache. If the cache is
+// not a VictimCache, every Set is treated as the Set of a new item and
+// the policy is never told of a victim.
+func NewAdmissionCache[K comparable, V any](cache Cache[K, V], admission AdmissionPolicy[K]) *AdmissionCache[K, V] {
+
+	victims, _ := cache.(VictimCache[K, V])
+	pinner, _ := cache.(victimPinner)
+
+	return &AdmissionCache[K, V]{
+		cache:      cache,
+		victims:    victims,
+		pinner:     pinner,
+		admission:  admission,
+		rejections: 0,
+	}
+}
+
+// Get records the request with the AdmissionPolicy and returns the
+// value of the item with the key from the wrapped cache.
+func (adapter *AdmissionCache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	adapter.admission.Record(key)
+
+	value, success = adapter.cache.Get(key, opts...)
+	adapter.missed_key, adapter.missed = key, !success
+
+	return value, success
+}
+
+// Set records the request with the AdmissionPolicy, unless it fetches
+// the item of a Get that just missed, and sets the item in the wrapped
+// cache if it is already there or the AdmissionPolicy admits it.
+// Returns false, and deletes any older value for the key, if the item
+// was rejected.
+func (adapter *AdmissionCache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
+
+	// a Set that fetches the item of a Get that just missed is part
+	// of the same request, so only other Sets are recorded
+	if !adapter.missed || adapter.missed_key != key {
+		adapter.admission.Record(key)
+	}
+	adapter.missed = false
+
+	// updates of items already in the cache are always set
+	if adapter.victims != nil && adapter.victims.Contains(key) {
+		return adapter.cache.Set(key, value, opts...)
+	}
+
+	var victim K
+	var has_victim bool
+	if adapter.victims != nil {
+		victim, has_victim = adapter.victims.Victim(description.size)
+	}
+
+	if !adapter.admission.Admit(key, description.size, victim, has_victim) {
+		if adapter.pinner != nil {
+			adapter.pinner.unpinVictim()
+		}
+		adapter.rejections++
+		adapter.cache.Delete(key)
+		return false, nil
+	}
+
+	return adapter.cache.Set(key, value, opts...)
+}
+
+// Delete removes the item with the given key from the wrapped cache.
+func (adapter *AdmissionCache[K, V]) Delete(key K) (success bool) {
+	return adapter.cache.Delete(key)
+}
+
+// Stats returns the statistics of the wrapped cache.
+func (adapter *AdmissionCache[K, V]) Stats() *Stats {
+	return adapter.cache.Stats()
+}
+
+// Rejections returns the number of new items the AdmissionPolicy rejected.
+func (adapter *AdmissionCache[K, V]) Rejections() int {
+	return adapter.rejections
+}
+
+// A victimPinner is a VictimCache that keeps the victim it picked for
+// the next eviction, which has to be forgotten if the item it was picked
+// for is rejected, so that a later Set does not evict it for nothing.
+type victimPinner interface {
+	unpinVictim()
+}
+
+/*********************************************************************/
+
+// number of hash functions of a Doorkeeper's bloom filter
+const doorkeeper_hashes = 4
+
+// A Doorkeeper is an AdmissionPolicy that keeps one-hit wonders, keys
+// that are only ever requested once, out of the cache. It remembers
+// every key it is asked to admit in a bloom filter and only admits keys
+// it has been asked about before. It forgets every key once it has
+// remembered as many keys as it was sized for, so that keys have to be
+// requested again within about that many other keys.
+type Doorkeeper[K comparable] struct {
+
+	// estimate of how many items fit in a cache whose
+	// max capacity counts bytes, or nil if it counts items
+	items *itemEstimate
+
+	// bits of the bloom filter, packed 64 to a word
+	filter []uint64
+
+	// number of bits in the filter, a power of two
+	filter_bits int
+
+	// number of keys to remember before forgetting them all
+	keys int
+
+	// number of keys remembered since the filter was last cleared
+	additions int
+}
+
+// NewDoorkeeper returns a Doorkeeper that remembers about the given
+// number of keys, such as the max capacity of the cache it guards,
+// with a false positive rate of about 1%. With WithByteCapacity, keys
+// is the max capacity in bytes of the cache it guards, and the
+// Doorkeeper starts small and grows to remember about as many keys as
+// items of the sizes it is asked to admit fit in the cache. Other
+// Options are ignored.
+func NewDoorkeeper[K comparable](keys int, opts ...Option) *Doorkeeper[K] {
+
+	items := newItemEstimate(keys, newOptions(opts))
+	if items != nil {
+		keys = 1
+	}
+
+	doorkeeper := &Doorkeeper[K]{items: items}
+	doorkeeper.resize(keys)
+
+	return doorkeeper
+}
+
+// resize makes the Doorkeeper remember about the given number of keys.
+// The filter starts over if it needs more bits.
+func (doorkeeper *Doorkeeper[K]) resize(keys int) {
+
+	keys = max(keys, 1)
+	doorkeeper.keys = keys
+
+	// ten bits per key gives about a 1% false positive rate
+	filter_bits := max(64, 1<<bits.Len(uint(10*keys-1)))
+
+	if filter_bits != doorkeeper.filter_bits {
+		doorkeeper.filter = make([]uint64, filter_bits/64)
+		doorkeeper.filter_bits = filter_bits
+		doorkeeper.additions = 0
+	}
+}
+
+// Record does nothing, since a Doorkeeper only counts keys it is asked to admit.
+func (doorkeeper *Doorkeeper[K]) Record(key K) {}
+
+// Admit remembers the key and returns true if it was already remembered.
+func (doorkeeper *Doorkeeper[K]) Admit(key K, size int, victim K, has_victim bool) bool {
+
+	if doorkeeper.items != nil {
+		if keys := doorkeeper.items.add(size); keys > doorkeeper.keys {
+			doorkeeper.resize(keys)
+		}
+	}
+
+	hash := keyHash(key)
+	seen := true
+
+	for row := 0; row < doorkeeper_hashes; row++ {
+		bit := int(rowHash(hash, row) & uint64(doorkeeper.filter_bits-1))
+		if doorkeeper.filter[bit/64]&(1<<(bit%64)) == 0 {
+			seen = false
+			doorkeeper.filter[bit/64] |= 1 << (bit % 64)
+		}
+	}
+
+	if !seen {
+		doorkeeper.additions++
+		if doorkeeper.additions > doorkeeper.keys {
+			clear(doorkeeper.filter)
+			doorkeeper.additions = 0
+		}
+	}
+
+	return seen
+}
+
+/*********************************************************************/
+
+// A SketchAdmission is an AdmissionPolicy that admits a new item only if
+// a count-min sketch of recent requests estimates that its key was
+// requested more often than the key of the item it would evict, like
+// the admission of a WTinyLFUCache. Items that fit without an eviction
+// are always admitted.
+type SketchAdmission[K comparable] struct {
+
+	// estimates of how often keys were recently requested
+	sketch *frequencySketch
+
+	// estimate of how many items fit in a cache whose
+	// max capacity counts bytes, or nil if it counts items
+	items *itemEstimate
+}
+
+// NewSketchAdmission returns a SketchAdmission whose sketch is sized for
+// about the given number of keys, such as the max capacity of the cache
+// it guards. With WithByteCapacity, keys is the max capacity in bytes
+// of the cache it guards, and the sketch starts small and grows to
+// about as many keys as items of the sizes it is asked to admit fit in
+// the cache. Other Options are ignored.
+func NewSketchAdmission[K comparable](keys int, opts ...Option) *SketchAdmission[K] {
+
+	items := newItemEstimate(keys, newOptions(opts))
+	if items != nil {
+		keys = 1
+	}
+
+	return &SketchAdmission[K]{sketch: newFrequencySketch(keys), items: items}
+}
+
+// Record counts a request for the key in the sketch.
+func (admission *SketchAdmission[K]) Record(key K) {
+	admission.sketch.increment(keyHash(key))
+}
+
+// Admit returns true if there is no victim or the key was requested
+// more often than the victim.
+func (admission *SketchAdmission[K]) Admit(key K, size int, victim K, has_victim bool) bool {
+
+	if admission.items != nil {
+		admission.sketch.grow(admission.items.add(size))
+	}
+
+	if !has_victim {
+		return true
+	}
+
+	return admission.sketch.estimate(keyHash(key)) > admission.sketch.estimate(keyHash(victim))
+}
+
+/*********************************************************************/
+
+// An itemEstimate estimates how many items fit in a cache whose max
+// capacity counts bytes, from the average size of the items an
+// AdmissionPolicy is asked to admit, so that the policy can be sized by
+// a number of items instead of a number of bytes.
+type itemEstimate struct {
+
+	// max capacity of the cache, in bytes
+	max_bytes int
+
+	// total size of the items asked about
+	total_size float64
+
+	// number of items asked about
+	asked int
+}
+
+// newItemEstimate returns an itemEstimate for a cache of max_bytes bytes
+// if the Options count max capacity in bytes, or nil if they count items.
+func newItemEstimate(max_bytes int, config *options) *itemEstimate {
+
+	if !config.by_bytes {
+		return nil
+	}
+
+	return &itemEstimate{max_bytes: max_bytes}
+}
+
+// add counts an item of the given size and returns the estimated number
+// of items that fit in the cache, which is at least 1.
+func (items *itemEstimate) add(size int) int {
+
+	items.total_size += float64(max(size, 1))
+	items.asked++
+
+	// the product of bytes and items can overflow an int
+	estimate := float64(items.max_bytes) / (items.total_size / float64(items.asked))
+
+	return int(max(min(estimate, float64(items.max_bytes)), 1))
+}
+
+/*********************************************************************/
+
+// A SizeThreshold is an AdmissionPolicy that only admits items no larger
+// than a maximum size, so that a few large items can not push out many
+// small ones.
+type SizeThreshold[K comparable] struct {
+
+	// size of the largest item admitted
+	max_size int
+}
+
+// NewSizeThreshold returns a SizeThreshold that admits items of at most max_size.
+func NewSizeThreshold[K comparable](max_size int) *SizeThreshold[K] {
+	return &SizeThreshold[K]{max_size: max_size}
+}
+
+// Record does nothing, since a SizeThreshold only looks at sizes.
+func (threshold *SizeThreshold[K]) Record(key K) {}
+
+// Admit returns true if the item is no larger than the maximum size.
+func (threshold *SizeThreshold[K]) Admit(key K, size int, victim K, has_victim bool) bool {
+	return size <= threshold.max_size
+}
@@ -0,0 +1,383 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// An ARCCache is a fixed-size, in-memory cache with adaptive replacement
+// (ARC) eviction. It keeps items that were used once (T1) apart from
+// items that were used again (T2), and remembers the keys it recently
+// evicted from each in ghost lists (B1 and B2). Setting a key that is
+// remembered in a ghost list moves the target size p of T1 towards the
+// list that would have kept it, so the cache adapts between recency and
+// frequency as the workload changes.
+type ARCCache[K comparable, V any] struct {
+
+	// total number of items (or bytes, in byte capacity
+	// mode) the ARCCache can store
+	max_capacity int
+
+	// whether max_capacity counts bytes instead of items
+	by_bytes bool
+
+	// target of how much of max_capacity the items in t1 use,
+	// between 0 and max_capacity
+	p int
+
+	// items used once since they were set, which are
+	// evicted to b1
+	t1 segment
+
+	// items used more than once since they were set,
+	// which are evicted to b2
+	t2 segment
+
+	// keys recently evicted from t1, without their values
+	b1 segment
+
+	// keys recently evicted from t2, without their values
+	b2 segment
+
+	// mapping of keys to items in any of the lists
+	keys_to_items map[K]*list.Element
+
+	// number of hits from the ARCCache
+	hits int
+
+	// number of misses from the ARCCache
+	misses int
+
+	// total size of the items hit in the ARCCache
+	hit_bytes int
+
+	// total size of the items missed in the ARCCache
+	miss_bytes int
+
+	// number of items evicted from the ARCCache
+	evictions int
+
+	// clock that tells the ARCCache when items expire
+	clock Clock
+}
+
+// A ARCCacheItem holds a key, value pair to be put in one of
+// the lists of an ARCCache.
+type ARCCacheItem[K comparable, V any] struct {
+	key        K
+	value      V
+	size       int
+	expires_at int
+
+	// list the item is in
+	location *segment
+}
+
+// NewARCCache returns a pointer to a new, empty ARCCache.
+func NewARCCache[K comparable, V any](max_capacity int, opts ...Option) (*ARCCache[K, V], error) {
+
+	if err := validateCapacity(max_capacity); err != nil {
+		return nil, err
+	}
+
+	config := newOptions(opts)
+
+	// create and initialize a new ARCCache
+	return &ARCCache[K, V]{
+		max_capacity:  max_capacity,
+		by_bytes:      config.by_bytes,
+		clock:         config.clock,
+		p:             0,
+		t1:            newSegment(),
+		t2:            newSegment(),
+		b1:            newSegment(),
+		b2:            newSegment(),
+		keys_to_items: make(map[K]*list.Element),
+		hits:          0,
+		misses:        0,
+	}, nil
+}
+
+// Get returns the value of the item with the key and a success boolean
+// indicating if the item was found. A key that is only remembered in a
+// ghost list is a miss.
+// This operation counts as a "use" for that item, moving it to T2.
+func (arc *ARCCache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	// read the time of this request
+	now := arc.clock.Now()
+
+	// check if there is an item in the cache with the given key
+	existing_item, ok := arc.keys_to_items[key]
+	if ok && !arc.resident(existing_item) {
+		ok = false
+	}
+
+	// an expired item is removed and counts as a miss
+	if ok && expired(existing_item.Value.(*ARCCacheItem[K, V]).expires_at, now) {
+		arc.remove(existing_item)
+		ok = false
+	}
+
+	if !ok {
+		arc.misses++
+		arc.miss_bytes += requestSize(opts)
+		return value, false
+	}
+
+	arc.hits++
+
+	// move the item to the most recently used end of t2
+	existing_item = arc.move(existing_item, &arc.t2)
+
+	item := existing_item.Value.(*ARCCacheItem[K, V])
+	arc.hit_bytes += item.size
+
+	return item.value, true
+}
+
+// Set sets the value of the item with the given key,
+// possibly evicting items to make room for a new key insertion.
+// This operation counts as a "use" for that item, so an item that is
+// already in the cache, or is remembered in a ghost list, moves to T2.
+// Returns true if the item was added/updated successfully, else false.
+func (arc *ARCCache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	// read the time of this request
+	operation_timestamp := arc.clock.Now()
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
+
+	// can not set if cache max capacity is 0!
+	if arc.max_capacity == 0 {
+		return false, nil
+	}
+
+	item_weight := weight(arc.by_bytes, description.size)
+
+	// check if there is an existing item with the key
+	existing_item, ok := arc.keys_to_items[key]
+
+	// an item larger than the whole cache can never fit,
+	// and any older value for its key is now stale
+	if item_weight > arc.max_capacity {
+		if ok && arc.resident(existing_item) {
+			arc.remove(existing_item)
+		}
+		return false, nil
+	}
+
+	if ok && arc.resident(existing_item) {
+		item := existing_item.Value.(*ARCCacheItem[K, V])
+		item.value = value
+		item.expires_at = expiry(operation_timestamp, description.ttl)
+
+		// move the item to t2, accounting for a change in
+		// size, and evict other items if it no longer fits
+		existing_item = arc.move(existing_item, &arc.t2)
+		arc.t2.used += item_weight - weight(arc.by_bytes, item.size)
+		item.size = description.size
+		arc.makeRoom(0, false, existing_item)
+		arc.forget()
+
+		return true, nil
+	}
+
+	// a key remembered in a ghost list would have been hit if
+	// its list were bigger, so grow the target of that list
+	// and bring the key back into t2
+	location := &arc.t1
+	in_b2 := false
+
+	if ok {
+		ghost := existing_item.Value.(*ARCCacheItem[K, V])
+
+		if ghost.location == &arc.b1 {
+			arc.p = min(arc.p+item_weight*max(arc.b2.used/arc.b1.used, 1), arc.max_capacity)
+		} else {
+			arc.p = max(arc.p-item_weight*max(arc.b1.used/arc.b2.used, 1), 0)
+			in_b2 = true
+		}
+
+		arc.remove(existing_item)
+		location = &arc.t2
+	}
+
+	// check if we need to evict
+	arc.makeRoom(item_weight, in_b2, nil)
+
+	// insert the item into its list and store the mapping
+	// of the pointer of the item into the mapping
+	item := &ARCCacheItem[K, V]{key: key, value: value, size: description.size,
+		expires_at: expiry(operation_timestamp, description.ttl), location: location}
+	arc.keys_to_items[key] = location.linked_list.PushBack(item)
+	location.used += item_weight
+
+	arc.forget()
+
+	return true, nil
+}
+
+// makeRoom evicts items until an item of the given weight fits in the
+// ARCCache. Items are evicted from t1 while it uses more than its target
+// p, or exactly p if the new item was remembered in b2, and from t2
+// otherwise. The protected item is never evicted.
+func (arc *ARCCache[K, V]) makeRoom(item_weight int, in_b2 bool, protected *list.Element) {
+
+	for arc.t1.used+arc.t2.used+item_weight > arc.max_capacity {
+
+		// evict the least recently used item of the list that
+		// is over its target, or of the other list if the
+		// picked one has nothing but the protected item
+		first, second := &arc.t2, &arc.t1
+		if arc.t1.used > arc.p || (in_b2 && arc.t1.used == arc.p) {
+			first, second = &arc.t1, &arc.t2
+		}
+
+		victim := leastRecent(first, protected)
+		if victim == nil {
+			victim = leastRecent(second, protected)
+		}
+
+		if victim == nil {
+			return
+		}
+
+		// remember the key of the evicted item in a ghost list
+		if victim.Value.(*ARCCacheItem[K, V]).location == &arc.t1 {
+			arc.move(victim, &arc.b1)
+		} else {
+			arc.move(victim, &arc.b2)
+		}
+
+		arc.evictions++
+	}
+}
+
+// forget drops the least recently evicted keys from the ghost lists
+// until t1 and b1 together use at most max_capacity and all four lists
+// together use at most twice max_capacity.
+func (arc *ARCCache[K, V]) forget() {
+
+	for arc.t1.used+arc.b1.used > arc.max_capacity && arc.b1.linked_list.Len() > 0 {
+		arc.remove(arc.b1.linked_list.Front())
+	}
+
+	for arc.t1.used+arc.t2.used+arc.b1.used+arc.b2.used > 2*arc.max_capacity {
+		ghost := arc.b2.linked_list.Front()
+		if ghost == nil {
+			ghost = arc.b1.linked_list.Front()
+		}
+
+		if ghost == nil {
+			return
+		}
+
+		arc.remove(ghost)
+	}
+}
+
+// Delete removes the item with the given key from the ARCCache.
+// Returns true if the item was found and removed, else false.
+func (arc *ARCCache[K, V]) Delete(key K) (success bool) {
+
+	// check if there is an item in the cache with the given key
+	existing_item, ok := arc.keys_to_items[key]
+	if !ok || !arc.resident(existing_item) {
+		return false
+	}
+
+	arc.remove(existing_item)
+
+	return true
+}
+
+// resident returns true if an item is in t1 or t2,
+// rather than remembered in a ghost list.
+func (arc *ARCCache[K, V]) resident(element *list.Element) bool {
+	location := element.Value.(*ARCCacheItem[K, V]).location
+	return location == &arc.t1 || location == &arc.t2
+}
+
+// move moves an item to the most recently used end of the given list
+// and returns its new element. Items moved to a ghost list drop their
+// values.
+func (arc *ARCCache[K, V]) move(element *list.Element, location *segment) *list.Element {
+
+	item := element.Value.(*ARCCacheItem[K, V])
+	item_weight := weight(arc.by_bytes, item.size)
+
+	if item.location == location {
+		location.linked_list.MoveToBack(element)
+		return element
+	}
+
+	item.location.linked_list.Remove(element)
+	item.location.used -= item_weight
+
+	if location == &arc.b1 || location == &arc.b2 {
+		var zero V
+		item.value = zero
+	}
+
+	item.location = location
+	location.used += item_weight
+
+	moved := location.linked_list.PushBack(item)
+	arc.keys_to_items[item.key] = moved
+
+	return moved
+}
+
+// remove unlinks an item from its list and the map.
+func (arc *ARCCache[K, V]) remove(element *list.Element) {
+
+	item := element.Value.(*ARCCacheItem[K, V])
+
+	item.location.linked_list.Remove(element)
+	item.location.used -= weight(arc.by_bytes, item.size)
+	delete(arc.keys_to_items, item.key)
+}
+
+// Contains returns true if an item with the key is in T1 or T2 of the
+// ARCCache, whether or not it has expired, without counting as a use.
+func (arc *ARCCache[K, V]) Contains(key K) bool {
+	existing_item, ok := arc.keys_to_items[key]
+	return ok && arc.resident(existing_item)
+}
+
+// Victim returns the key of the item the ARCCache would evict first to
+// make room for a new item of the given size that is not remembered in
+// B2, and true, or false if the item fits without evicting anything.
+func (arc *ARCCache[K, V]) Victim(size int) (key K, ok bool) {
+
+	if arc.t1.used+arc.t2.used+weight(arc.by_bytes, size) <= arc.max_capacity {
+		return key, false
+	}
+
+	// pick from the lists in the same order as makeRoom
+	first, second := &arc.t2, &arc.t1
+	if arc.t1.used > arc.p {
+		first, second = &arc.t1, &arc.t2
+	}
+
+	victim := leastRecent(first, nil)
+	if victim == nil {
+		victim = leastRecent(second, nil)
+	}
+
+	if victim == nil {
+		return key, false
+	}
+
+	return victim.Value.(*ARCCacheItem[K, V]).key, true
+}
+
+// Stats returns statistics about how many search hits and misses have
+// occurred in the ARCCache.
+func (arc *ARCCache[K, V]) Stats() *Stats {
+	return &Stats{Hits: arc.hits, Misses: arc.misses,
+		HitBytes: arc.hit_bytes, MissBytes: arc.miss_bytes, Evictions: arc.evictions}
+}
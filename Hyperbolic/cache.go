@@ -1,22 +1,229 @@
 package cache
 
+import (
+	"container/list"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidCapacity is returned when a cache is created
+	// with a max capacity it can not use.
+	ErrInvalidCapacity = errors.New("invalid max capacity")
+
+	// ErrInvalidSampleSize is returned when a sampling cache is created
+	// with a sample size it can not use.
+	ErrInvalidSampleSize = errors.New("invalid sample size")
+
+	// ErrInvalidPriority is returned when a sampling cache
+	// is created without a priority function.
+	ErrInvalidPriority = errors.New("invalid priority function")
+
+	// ErrInvalidAging is returned when a LFU cache is created
+	// with an aging mode it can not use.
+	ErrInvalidAging = errors.New("invalid aging")
+
+	// ErrInvalidItemOption is returned by Set when it is given an
+	// ItemOption with a value it can not use.
+	ErrInvalidItemOption = errors.New("invalid item option")
+
+	// ErrNegativeValue is returned alongside the errors above
+	// when the offending value is negative.
+	ErrNegativeValue = errors.New("negative value")
+
+	// ErrInconsistentState is returned by Set when a cache finds its
+	// internal bookkeeping in a state that should be impossible.
+	ErrInconsistentState = errors.New("inconsistent cache state")
+)
+
+// validateCapacity returns an error if max_capacity can not be
+// used as the max capacity of a cache.
+func validateCapacity(max_capacity int) error {
+	if max_capacity < 0 {
+		return fmt.Errorf("%w %d: %w", ErrInvalidCapacity, max_capacity, ErrNegativeValue)
+	}
+
+	return nil
+}
+
+// weight returns how much of a cache's max capacity an item of the
+// given size uses up: its size in byte capacity mode, or else 1.
+func weight(by_bytes bool, size int) int {
+	if by_bytes {
+		return size
+	}
+
+	return 1
+}
+
+// expiry returns when an item set at the given time with the
+// given TTL expires, or 0 if it never expires.
+func expiry(operation_timestamp int, ttl int) int {
+	if ttl == 0 {
+		return 0
+	}
+
+	return operation_timestamp + ttl
+}
+
+// expired returns true if an item that expires at expires_at
+// has expired by the given time.
+func expired(expires_at int, now int) bool {
+	return expires_at != 0 && now >= expires_at
+}
+
+// A segment is one of the linked lists of a cache that splits its
+// items between several lists.
+type segment struct {
+
+	// linked list of items, least recently used first
+	linked_list *list.List
+
+	// how much of the cache's max capacity the items in the list use
+	used int
+}
+
+// newSegment returns a new, empty segment.
+func newSegment() segment {
+	return segment{linked_list: list.New()}
+}
+
+// leastRecent returns the least recently used item of the segment other
+// than the protected item, or nil if there is no such item.
+func leastRecent(location *segment, protected *list.Element) *list.Element {
+
+	element := location.linked_list.Front()
+	if element != nil && element == protected {
+		element = element.Next()
+	}
+
+	return element
+}
+
+// requestSize returns the size of a requested item given to Get with
+// WithSize, or the default size of 1 if no valid size was given.
+func requestSize(opts []ItemOption) int {
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return 1
+	}
+
+	return description.size
+}
+
 type Stats struct {
 	Hits   int
 	Misses int
+
+	// total size of the items that were hit
+	HitBytes int
+
+	// total size of the items that were missed, as given to Get
+	MissBytes int
+
+	// number of items evicted to make room for other items
+	Evictions int
+}
+
+// HitRatio returns the fraction of requests that were hits.
+func (stats *Stats) HitRatio() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// ByteHitRatio returns the fraction of requested bytes that were hits.
+func (stats *Stats) ByteHitRatio() float64 {
+	if stats.HitBytes+stats.MissBytes == 0 {
+		return 0
+	}
+
+	return float64(stats.HitBytes) / float64(stats.HitBytes+stats.MissBytes)
 }
 
-type Cache interface {
+// A Cache stores values of type V under keys of type K.
+type Cache[K comparable, V any] interface {
+
+	// Get returns the value stored under the given key and true if
+	// an unexpired item with the key was found in the cache, or the
+	// zero value and false otherwise. The size of the requested item
+	// can be given with WithSize so that a miss counts its bytes.
+	Get(key K, opts ...ItemOption) (value V, success bool)
+
+	// Set adds or updates an item with the given key and value in the
+	// cache and returns true if a successful update was made, false
+	// otherwise. ItemOptions describe the item being set. A non-nil
+	// error means an ItemOption was invalid or the cache found its
+	// internal state to be inconsistent.
+	Set(key K, value V, opts ...ItemOption) (success bool, err error)
+
+	// Delete removes the item with the given key from the cache and
+	// returns true if such an item was found, false otherwise.
+	Delete(key K) (success bool)
+
+	// Stats returns a pointer to a Stats object that indicates how many hits
+	// and misses (and how many bytes of each) this cache has resolved, and
+	// how many items it has evicted, over its lifetime.
+	Stats() *Stats
+}
+
+// A KeyCache is a cache that only tracks which keys are present,
+// which is all the trace simulator needs.
+type KeyCache interface {
 
 	// Get returns true if an item with the given
 	// key was found in the cache, false otherwise.
-	Get(key string) (success bool)
+	// The size of the requested item can be given
+	// with WithSize so that a miss counts its bytes.
+	Get(key string, opts ...ItemOption) (success bool)
 
 	// Set adds or updates an item with the given key in the
 	// cache and returns true if a successful update was
-	// made, false otherwise.
-	Set(operation_timestamp int, key string) (success bool)
+	// made, false otherwise. ItemOptions describe the item
+	// being set. A non-nil error means an ItemOption was
+	// invalid or the cache found its internal state to be
+	// inconsistent.
+	Set(key string, opts ...ItemOption) (success bool, err error)
+
+	// Delete removes the item with the given key from the cache and
+	// returns true if such an item was found, false otherwise.
+	Delete(key string) (success bool)
 
 	// Stats returns a pointer to a Stats object that indicates how many hits
 	// and misses this cache has resolved over its lifetime.
 	Stats() *Stats
 }
+
+// keyCache adapts a Cache holding empty values to the KeyCache interface.
+type keyCache struct {
+	cache Cache[string, struct{}]
+}
+
+// NewKeyCache returns a KeyCache backed by the given cache.
+func NewKeyCache(cache Cache[string, struct{}]) KeyCache {
+	return &keyCache{cache: cache}
+}
+
+// Get returns true if an item with the given key was found in the cache.
+func (adapter *keyCache) Get(key string, opts ...ItemOption) (success bool) {
+	_, success = adapter.cache.Get(key, opts...)
+	return success
+}
+
+// Set adds or updates an item with the given key in the cache.
+func (adapter *keyCache) Set(key string, opts ...ItemOption) (success bool, err error) {
+	return adapter.cache.Set(key, struct{}{}, opts...)
+}
+
+// Delete removes the item with the given key from the cache.
+func (adapter *keyCache) Delete(key string) (success bool) {
+	return adapter.cache.Delete(key)
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (adapter *keyCache) Stats() *Stats {
+	return adapter.cache.Stats()
+}
@@ -8,10 +8,25 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"testing"
 )
 
+/******************************************************************************/
+/*                                 Helpers                                    */
+/******************************************************************************/
+
+// mustCache returns the given cache, panicking if it could not be created.
+func mustCache[C any](cache C, err error) C {
+	if err != nil {
+		panic(err)
+	}
+	return cache
+}
+
 /******************************************************************************/
 /*                                  Tests                                     */
 /******************************************************************************/
@@ -20,38 +35,42 @@ import (
 func Test_CreateHyperbolic(t *testing.T) {
 	max_capacity := 50
 	sample_size := 50
-	hyperbolic := NewHyperbolicCache(max_capacity, sample_size)
+	hyperbolic := mustCache(NewHyperbolicCache[string, int](max_capacity, sample_size))
 	for i := 0; i < max_capacity; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := hyperbolic.Set(0, key)
-		if !set_success {
+		set_success, err := hyperbolic.Set(key, i)
+		if err != nil || !set_success {
 			t.Errorf("Failed to set binding with key: %s", key)
 			t.FailNow()
 		}
-		get_success := hyperbolic.Get(key)
+		value, get_success := hyperbolic.Get(key)
 		if !get_success {
 			t.Errorf("Failed to get binding with key: %s", key)
 			t.FailNow()
 		}
+		if value != i {
+			t.Errorf("Expected value %d for key %s, got %d", i, key, value)
+			t.FailNow()
+		}
 	}
 }
 
 // Tests a hyperbolic cache with a max capacity of 0 items.
 func Test_EmptyHyperbolic(t *testing.T) {
 	max_capacity := 0
-	hyperbolic := NewHyperbolicCache(max_capacity, 0)
+	hyperbolic := mustCache(NewHyperbolicCache[string, int](max_capacity, 0))
 
 	// try to set bindings
 	for i := 0; i < 3; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := hyperbolic.Set(i, key)
+		set_success, err := hyperbolic.Set(key, 0)
 
-		if set_success {
+		if err != nil || set_success {
 			t.Errorf("This set operation should have failed!")
 			t.FailNow()
 		}
 
-		get_success := hyperbolic.Get(key)
+		_, get_success := hyperbolic.Get(key)
 		if get_success {
 			t.Errorf("This get operation should have failed!")
 			t.FailNow()
@@ -63,24 +82,27 @@ func Test_EmptyHyperbolic(t *testing.T) {
 func Test_HyperbolicEviction(t *testing.T) {
 	max_capacity := 3
 	sample_size := 3
-	hyperbolic := NewHyperbolicCache(max_capacity, sample_size)
+	clock := NewManualClock(0)
+	hyperbolic := mustCache(NewHyperbolicCache[string, int](max_capacity, sample_size, WithClock(clock)))
 
 	for i := 0; i < 3; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := hyperbolic.Set(i, key)
-		if !set_success {
+		clock.Set(i)
+		set_success, err := hyperbolic.Set(key, 0)
+		if err != nil || !set_success {
 			t.Errorf("Failed to set binding with key: %s", key)
 			t.FailNow()
 		}
 	}
 
-	set_success := hyperbolic.Set(5, "A")
-	if !set_success {
+	clock.Set(5)
+	set_success, err := hyperbolic.Set("A", 0)
+	if err != nil || !set_success {
 		t.Errorf("Failed to set binding with key: %s", "A")
 		t.FailNow()
 	}
 
-	get_success := hyperbolic.Get("0")
+	_, get_success := hyperbolic.Get("0")
 	if get_success {
 		t.Errorf("Item with key '0' should have been evicted.")
 		t.FailNow()
@@ -95,7 +117,8 @@ func CheckHyperbolicFunction(t *testing.T) {
 	// sample size is the same as max capacity to make sure we
 	// calculate the priority of all items (so that we can
 	// check accuracy)
-	hyperbolic := NewHyperbolicCache(max_capacity, max_capacity)
+	clock := NewManualClock(0)
+	hyperbolic := mustCache(NewHyperbolicCache[string, int](max_capacity, max_capacity, WithClock(clock)))
 
 	var test_values [5]string
 	test_values[0] = "a"
@@ -107,8 +130,9 @@ func CheckHyperbolicFunction(t *testing.T) {
 	// set bindings
 	for i := 0; i < 5; i++ {
 		key := test_values[i]
-		set_success := hyperbolic.Set(i, key)
-		if !set_success {
+		clock.Set(i)
+		set_success, err := hyperbolic.Set(key, 0)
+		if err != nil || !set_success {
 			t.Errorf("Failed to set binding with key: %s", key)
 			t.FailNow()
 		}
@@ -122,21 +146,22 @@ func CheckHyperbolicFunction(t *testing.T) {
 	hyperbolic.Get("a")
 
 	// try to set when the cache is full
-	hyperbolic.Set(5, "f")
+	clock.Set(5)
+	hyperbolic.Set("f", 0)
 
 	// item with key 'a' should be evicted
 	for keys := range hyperbolic.keys_to_items {
 		fmt.Println(keys)
 	}
 
-	get_success1 := hyperbolic.Get("f")
+	_, get_success1 := hyperbolic.Get("f")
 
 	if !get_success1 {
 		t.Errorf("Item with key 'f' should be in the cache!")
 		t.FailNow()
 	}
 
-	get_success2 := hyperbolic.Get("a")
+	_, get_success2 := hyperbolic.Get("a")
 
 	if get_success2 {
 		t.Errorf("Item with key 'a' should have been evicted!")
@@ -147,7 +172,8 @@ func CheckHyperbolicFunction(t *testing.T) {
 // Test whether the hyperbolic cache is working (no setting).
 func TestHyperbolicFunction2(t *testing.T) {
 	capacity := 5
-	cache := NewHyperbolicCache(capacity, capacity)
+	clock := NewManualClock(0)
+	cache := mustCache(NewHyperbolicCache[string, int](capacity, capacity, WithClock(clock)))
 
 	var values [5]string
 	values[0] = "a"
@@ -158,17 +184,18 @@ func TestHyperbolicFunction2(t *testing.T) {
 
 	for i := 0; i < 5; i++ {
 		key := values[i]
-		timestamp := (i + 1) * 2
-		ok := cache.Set(timestamp, key)
-		if !ok {
+		clock.Set((i + 1) * 2)
+		ok, err := cache.Set(key, 0)
+		if err != nil || !ok {
 			t.Errorf("Failed to add binding with key: %s", key)
 			t.FailNow()
 		}
 	}
 
-	cache.Set((5+1)*2, "f")
+	clock.Set((5 + 1) * 2)
+	cache.Set("f", 0)
 
-	ok := cache.Get("a")
+	_, ok := cache.Get("a")
 
 	if ok {
 		t.Errorf("a should have been evicted.")
@@ -179,20 +206,22 @@ func TestHyperbolicFunction2(t *testing.T) {
 // Test whether the hyperbolic cache is working (evicting newly entered).
 func TestHyperbolicFunction3(t *testing.T) {
 	capacity := 5
-	cache := NewHyperbolicCache(capacity, capacity)
+	clock := NewManualClock(0)
+	cache := mustCache(NewHyperbolicCache[string, int](capacity, capacity, WithClock(clock)))
 
 	var values [5]string
 	values[0] = "a"
 	values[1] = "b"
+	values[2] = "c"
 	values[3] = "d"
 	values[4] = "e"
 
 	// set bindings
 	for i := 0; i < 5; i++ {
 		key := values[i]
-		timestamp := (i + 1) * 2
-		ok := cache.Set(timestamp, key)
-		if !ok {
+		clock.Set((i + 1) * 2)
+		ok, err := cache.Set(key, 0)
+		if err != nil || !ok {
 			t.Errorf("Failed to add binding with key: %s", key)
 			t.FailNow()
 		}
@@ -205,9 +234,10 @@ func TestHyperbolicFunction3(t *testing.T) {
 		cache.Get("d")
 	}
 
-	cache.Set((5+1)*2, "f")
+	clock.Set((5 + 1) * 2)
+	cache.Set("f", 0)
 
-	ok := cache.Get("e")
+	_, ok := cache.Get("e")
 
 	if ok {
 		t.Errorf("e should have been evicted.")
@@ -220,38 +250,42 @@ func TestHyperbolicFunction3(t *testing.T) {
 // Tests the creation of a FIFO cache. Then performs set and get operations.
 func Test_CreateFIFO(t *testing.T) {
 	max_capacity := 50
-	fifo := NewFIFOCache(max_capacity)
+	fifo := mustCache(NewFIFOCache[string, int](max_capacity))
 	for i := 0; i < max_capacity; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := fifo.Set(0, key)
-		if !set_success {
+		set_success, err := fifo.Set(key, i)
+		if err != nil || !set_success {
 			t.Errorf("Failed to set binding with key: %s", key)
 			t.FailNow()
 		}
-		get_success := fifo.Get(key)
+		value, get_success := fifo.Get(key)
 		if !get_success {
 			t.Errorf("Failed to get binding with key: %s", key)
 			t.FailNow()
 		}
+		if value != i {
+			t.Errorf("Expected value %d for key %s, got %d", i, key, value)
+			t.FailNow()
+		}
 	}
 }
 
 // Tests a FIFO cache with a max capacity of 0 items.
 func Test_EmptyFIFO(t *testing.T) {
 	max_capacity := 0
-	fifo := NewFIFOCache(max_capacity)
+	fifo := mustCache(NewFIFOCache[string, int](max_capacity))
 
 	// try to set bindings
 	for i := 0; i < 3; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := fifo.Set(0, key)
+		set_success, err := fifo.Set(key, 0)
 
-		if set_success {
+		if err != nil || set_success {
 			t.Errorf("This set operation should have failed!")
 			t.FailNow()
 		}
 
-		get_success := fifo.Get(key)
+		_, get_success := fifo.Get(key)
 		if get_success {
 			t.Errorf("This get operation should have failed!")
 			t.FailNow()
@@ -262,36 +296,36 @@ func Test_EmptyFIFO(t *testing.T) {
 // Checks to see that FIFO eviction is occuring at all and accurately.
 func Test_FIFOEviction(t *testing.T) {
 	max_capacity := 3
-	fifo := NewFIFOCache(max_capacity)
+	fifo := mustCache(NewFIFOCache[string, int](max_capacity))
 
 	for i := 0; i < 3; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := fifo.Set(0, key)
-		if !set_success {
+		set_success, err := fifo.Set(key, 0)
+		if err != nil || !set_success {
 			t.Errorf("Failed to set binding with key: %s", key)
 			t.FailNow()
 		}
 	}
 
-	set_success := fifo.Set(0, "A")
-	if !set_success {
+	set_success, err := fifo.Set("A", 0)
+	if err != nil || !set_success {
 		t.Errorf("Failed to set binding with key: %s", "A")
 		t.FailNow()
 	}
 
-	get_success := fifo.Get("0")
+	_, get_success := fifo.Get("0")
 	if get_success {
 		t.Errorf("Item with key '0' should have been evicted.")
 		t.FailNow()
 	}
 
-	set_success2 := fifo.Set(0, "B")
-	if !set_success2 {
+	set_success2, err := fifo.Set("B", 0)
+	if err != nil || !set_success2 {
 		t.Errorf("Failed to set binding with key: %s", "B")
 		t.FailNow()
 	}
 
-	get_success2 := fifo.Get("1")
+	_, get_success2 := fifo.Get("1")
 	if get_success2 {
 		t.Errorf("Item with key '1' should have been evicted.")
 		t.FailNow()
@@ -303,38 +337,42 @@ func Test_FIFOEviction(t *testing.T) {
 // Tests the creation of a LRU cache. Then performs set and get operations.
 func Test_CreateLRU(t *testing.T) {
 	max_capacity := 50
-	lru := NewLRUCache(max_capacity)
+	lru := mustCache(NewLRUCache[string, int](max_capacity))
 	for i := 0; i < max_capacity; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := lru.Set(0, key)
-		if !set_success {
+		set_success, err := lru.Set(key, i)
+		if err != nil || !set_success {
 			t.Errorf("Failed to set binding with key: %s", key)
 			t.FailNow()
 		}
-		get_success := lru.Get(key)
+		value, get_success := lru.Get(key)
 		if !get_success {
 			t.Errorf("Failed to get binding with key: %s", key)
 			t.FailNow()
 		}
+		if value != i {
+			t.Errorf("Expected value %d for key %s, got %d", i, key, value)
+			t.FailNow()
+		}
 	}
 }
 
 // Tests a LRU cache with a max capacity of 0 items.
 func Test_EmptyLRU(t *testing.T) {
 	max_capacity := 0
-	lru := NewLRUCache(max_capacity)
+	lru := mustCache(NewLRUCache[string, int](max_capacity))
 
 	// try to set bindings
 	for i := 0; i < 3; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := lru.Set(0, key)
+		set_success, err := lru.Set(key, 0)
 
-		if set_success {
+		if err != nil || set_success {
 			t.Errorf("This set operation should have failed!")
 			t.FailNow()
 		}
 
-		get_success := lru.Get(key)
+		_, get_success := lru.Get(key)
 		if get_success {
 			t.Errorf("This get operation should have failed!")
 			t.FailNow()
@@ -345,12 +383,12 @@ func Test_EmptyLRU(t *testing.T) {
 // Checks to see that LRU eviction is occuring at all and accurately.
 func Test_LRUEviction(t *testing.T) {
 	max_capacity := 3
-	lru := NewLRUCache(max_capacity)
+	lru := mustCache(NewLRUCache[string, int](max_capacity))
 
 	for i := 0; i < 3; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := lru.Set(0, key)
-		if !set_success {
+		set_success, err := lru.Set(key, 0)
+		if err != nil || !set_success {
 			t.Errorf("Failed to set binding with key: %s", key)
 			t.FailNow()
 		}
@@ -358,25 +396,25 @@ func Test_LRUEviction(t *testing.T) {
 
 	lru.Get("0")
 
-	set_success := lru.Set(0, "A")
-	if !set_success {
+	set_success, err := lru.Set("A", 0)
+	if err != nil || !set_success {
 		t.Errorf("Failed to set binding with key: %s", "A")
 		t.FailNow()
 	}
 
-	get_success := lru.Get("1")
+	_, get_success := lru.Get("1")
 	if get_success {
 		t.Errorf("Item with key '1' should have been evicted.")
 		t.FailNow()
 	}
 
-	set_success2 := lru.Set(0, "B")
-	if !set_success2 {
+	set_success2, err := lru.Set("B", 0)
+	if err != nil || !set_success2 {
 		t.Errorf("Failed to set binding with key: %s", "B")
 		t.FailNow()
 	}
 
-	get_success2 := lru.Get("2")
+	_, get_success2 := lru.Get("2")
 	if get_success2 {
 		t.Errorf("Item with key '2' should have been evicted.")
 		t.FailNow()
@@ -388,38 +426,42 @@ func Test_LRUEviction(t *testing.T) {
 // Tests the creation of a LFU cache. Then performs set and get operations.
 func Test_CreateLFU(t *testing.T) {
 	max_capacity := 50
-	lfu := NewLRUCache(max_capacity)
+	lfu := mustCache(NewLRUCache[string, int](max_capacity))
 	for i := 0; i < max_capacity; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := lfu.Set(0, key)
-		if !set_success {
+		set_success, err := lfu.Set(key, i)
+		if err != nil || !set_success {
 			t.Errorf("Failed to set binding with key: %s", key)
 			t.FailNow()
 		}
-		get_success := lfu.Get(key)
+		value, get_success := lfu.Get(key)
 		if !get_success {
 			t.Errorf("Failed to get binding with key: %s", key)
 			t.FailNow()
 		}
+		if value != i {
+			t.Errorf("Expected value %d for key %s, got %d", i, key, value)
+			t.FailNow()
+		}
 	}
 }
 
 // Tests a LFU cache with a max capacity of 0 items.
 func Test_EmptyLFU(t *testing.T) {
 	max_capacity := 0
-	lfu := NewLRUCache(max_capacity)
+	lfu := mustCache(NewLRUCache[string, int](max_capacity))
 
 	// try to set bindings
 	for i := 0; i < 3; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := lfu.Set(0, key)
+		set_success, err := lfu.Set(key, 0)
 
-		if set_success {
+		if err != nil || set_success {
 			t.Errorf("This set operation should have failed!")
 			t.FailNow()
 		}
 
-		get_success := lfu.Get(key)
+		_, get_success := lfu.Get(key)
 		if get_success {
 			t.Errorf("This get operation should have failed!")
 			t.FailNow()
@@ -430,12 +472,12 @@ func Test_EmptyLFU(t *testing.T) {
 // Checks to see that LFU eviction is occuring at all and accurately.
 func Test_LFUEviction(t *testing.T) {
 	max_capacity := 5
-	lfu := NewLFUCache(max_capacity)
+	lfu := mustCache(NewLFUCache[string, int](max_capacity))
 
 	for i := 0; i < 5; i++ {
 		key := fmt.Sprintf("%d", i)
-		set_success := lfu.Set(0, key)
-		if !set_success {
+		set_success, err := lfu.Set(key, 0)
+		if err != nil || !set_success {
 			t.Errorf("Failed to set binding with key: %s", key)
 			t.FailNow()
 		}
@@ -446,35 +488,35 @@ func Test_LFUEviction(t *testing.T) {
 	lfu.Get("0")
 	lfu.Get("0")
 	lfu.Get("0")
-	lfu.Set(0, "1")
+	lfu.Set("1", 0)
 	lfu.Get("2")
 	lfu.Get("1")
 	lfu.Get("3")
-	lfu.Set(0, "3")
+	lfu.Set("3", 0)
 	lfu.Get("3")
 	lfu.Get("1")
 	lfu.Get("1")
 	lfu.Get("2")
 
-	set_success := lfu.Set(0, "A")
-	if !set_success {
+	set_success, err := lfu.Set("A", 0)
+	if err != nil || !set_success {
 		t.Errorf("Failed to set binding with key: %s", "A")
 		t.FailNow()
 	}
 
-	get_success := lfu.Get("4")
+	_, get_success := lfu.Get("4")
 	if get_success {
 		t.Errorf("Item with key '4' should have been evicted.")
 		t.FailNow()
 	}
 
-	set_success2 := lfu.Set(0, "B")
-	if !set_success2 {
+	set_success2, err := lfu.Set("B", 0)
+	if err != nil || !set_success2 {
 		t.Errorf("Failed to set binding with key: %s", "B")
 		t.FailNow()
 	}
 
-	get_success2 := lfu.Get("A")
+	_, get_success2 := lfu.Get("A")
 	if get_success2 {
 		t.Errorf("Item with key 'A' should have been evicted.")
 		t.FailNow()
@@ -484,7 +526,7 @@ func Test_LFUEviction(t *testing.T) {
 // Tests whether the LFU cache is working.
 func TestLFUFunction(t *testing.T) {
 	capacity := 5
-	cache := NewLFUCache(capacity)
+	cache := mustCache(NewLFUCache[string, int](capacity))
 
 	var values [5]string
 	values[0] = "a"
@@ -495,8 +537,8 @@ func TestLFUFunction(t *testing.T) {
 
 	for i := 0; i < 5; i++ {
 		key := values[i]
-		ok := cache.Set(i, key)
-		if !ok {
+		ok, err := cache.Set(key, 0)
+		if err != nil || !ok {
 			t.Errorf("Failed to add binding with key: %s", values[i])
 			t.FailNow()
 		}
@@ -508,17 +550,17 @@ func TestLFUFunction(t *testing.T) {
 	cache.Get("d")
 	cache.Get("e")
 
-	cache.Set(1, "f")
+	cache.Set("f", 0)
 
-	ok := cache.Get("c")
+	_, ok := cache.Get("c")
 
 	if ok {
 		t.Errorf("c should have been evicted.")
 		t.FailNow()
 	}
-	cache.Set(1, "g")
+	cache.Set("g", 0)
 
-	ok = cache.Get("f")
+	_, ok = cache.Get("f")
 
 	if ok {
 		t.Errorf("f should have been evicted.")
@@ -530,12 +572,1976 @@ func TestLFUFunction(t *testing.T) {
 	cache.Get("d")
 	cache.Get("e")
 
-	cache.Set(1, "h")
+	cache.Set("h", 0)
 
-	ok = cache.Get("b")
+	_, ok = cache.Get("b")
 
 	if ok {
 		t.Errorf("b should have been evicted.")
 		t.FailNow()
 	}
 }
+
+/*********************************************************************/
+
+// Tests the creation of an ARC cache. Then performs set and get operations.
+func Test_CreateARC(t *testing.T) {
+	max_capacity := 50
+	arc := mustCache(NewARCCache[string, int](max_capacity))
+	for i := 0; i < max_capacity; i++ {
+		key := fmt.Sprintf("%d", i)
+		set_success, err := arc.Set(key, i)
+		if err != nil || !set_success {
+			t.Errorf("Failed to set binding with key: %s", key)
+			t.FailNow()
+		}
+		value, get_success := arc.Get(key)
+		if !get_success {
+			t.Errorf("Failed to get binding with key: %s", key)
+			t.FailNow()
+		}
+		if value != i {
+			t.Errorf("Expected value %d for key %s, got %d", i, key, value)
+			t.FailNow()
+		}
+	}
+}
+
+// Checks to see that ARC eviction keeps items used more than once over
+// a scan of items used only once.
+func Test_ARCEviction(t *testing.T) {
+	max_capacity := 3
+	arc := mustCache(NewARCCache[string, int](max_capacity))
+
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("%d", i)
+		set_success, err := arc.Set(key, 0)
+		if err != nil || !set_success {
+			t.Errorf("Failed to set binding with key: %s", key)
+			t.FailNow()
+		}
+	}
+
+	arc.Get("0")
+
+	set_success, err := arc.Set("A", 0)
+	if err != nil || !set_success {
+		t.Errorf("Failed to set binding with key: %s", "A")
+		t.FailNow()
+	}
+
+	_, get_success := arc.Get("1")
+	if get_success {
+		t.Errorf("Item with key '1' should have been evicted.")
+		t.FailNow()
+	}
+
+	// a scan evicts the items used once, but not '0'
+	arc.Set("B", 0)
+	arc.Set("C", 0)
+
+	for _, key := range []string{"2", "A"} {
+		if _, ok := arc.Get(key); ok {
+			t.Errorf("Item with key '%s' should have been evicted.", key)
+			t.FailNow()
+		}
+	}
+
+	if _, ok := arc.Get("0"); !ok {
+		t.Errorf("Item with key '0' should be in the cache!")
+		t.FailNow()
+	}
+}
+
+// Tests that setting a key remembered in a ghost list moves the target
+// size of T1 towards the list the key was evicted from.
+func Test_ARCAdaptation(t *testing.T) {
+	arc := mustCache(NewARCCache[string, int](3))
+
+	// 'c' is used twice, and 'f' and 'g' evict 'd' and 'e' from T1
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		arc.Set(key, 0)
+	}
+	arc.Get("c")
+	arc.Set("f", 0)
+	arc.Set("g", 0)
+
+	// 'e' is remembered in B1, so T1 should grow
+	arc.Set("e", 0)
+
+	if arc.p != 1 || arc.t1.used != 1 || arc.t2.used != 2 {
+		t.Errorf("Expected a target of 1 with 1 item in T1 and 2 in T2, got %d, %d and %d",
+			arc.p, arc.t1.used, arc.t2.used)
+		t.FailNow()
+	}
+
+	for key, present := range map[string]bool{"c": true, "e": true, "g": true, "f": false} {
+		element, ok := arc.keys_to_items[key]
+		if resident := ok && arc.resident(element); resident != present {
+			t.Errorf("Expected item with key '%s' to be in the cache: %t", key, present)
+			t.FailNow()
+		}
+	}
+
+	// 'c' is evicted from T2 to B2, and setting it again shrinks T1
+	arc.Get("g")
+	arc.Set("h", 0)
+	arc.Set("c", 0)
+
+	if arc.p != 0 {
+		t.Errorf("Expected a target of 0, got %d", arc.p)
+		t.FailNow()
+	}
+
+	if _, ok := arc.Get("c"); !ok {
+		t.Errorf("Item with key 'c' should be in the cache!")
+		t.FailNow()
+	}
+
+	if _, ok := arc.Get("h"); ok {
+		t.Errorf("Item with key 'h' should have been evicted.")
+		t.FailNow()
+	}
+
+	// keys in ghost lists are misses and can not be deleted
+	if arc.Delete("h") || arc.Stats().Misses != 1 {
+		t.Errorf("Expected 'h' to be a miss that can not be deleted")
+		t.FailNow()
+	}
+}
+
+/*********************************************************************/
+
+// Tests the creation of a W-TinyLFU cache. Then performs set and get operations.
+func Test_CreateWTinyLFU(t *testing.T) {
+	max_capacity := 50
+	wtl := mustCache(NewWTinyLFUCache[string, int](max_capacity))
+	for i := 0; i < max_capacity; i++ {
+		key := fmt.Sprintf("%d", i)
+		set_success, err := wtl.Set(key, i)
+		if err != nil || !set_success {
+			t.Errorf("Failed to set binding with key: %s", key)
+			t.FailNow()
+		}
+		value, get_success := wtl.Get(key)
+		if !get_success {
+			t.Errorf("Failed to get binding with key: %s", key)
+			t.FailNow()
+		}
+		if value != i {
+			t.Errorf("Expected value %d for key %s, got %d", i, key, value)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a W-TinyLFU cache only admits items from its window to its
+// main region if they were accessed more often than the item they evict.
+func Test_WTinyLFUAdmission(t *testing.T) {
+
+	// a window of 1 item and a main region of 9
+	wtl := mustCache(NewWTinyLFUCache[string, int](10))
+
+	// 'hot' is pushed into probation by 's0', and hits move it to protected
+	wtl.Set("hot", 0)
+	wtl.Set("s0", 0)
+	for i := 0; i < 5; i++ {
+		wtl.Get("hot")
+	}
+
+	if wtl.protected.linked_list.Len() != 1 {
+		t.Errorf("Item with key 'hot' should be protected.")
+		t.FailNow()
+	}
+
+	// a scan fills the main region, and then every candidate of the
+	// scan loses to the least recently used item of probation
+	for i := 1; i < 30; i++ {
+		set_success, err := wtl.Set(fmt.Sprintf("s%d", i), 0)
+		if err != nil || !set_success {
+			t.Errorf("Failed to set binding with key: s%d", i)
+			t.FailNow()
+		}
+	}
+
+	for key, present := range map[string]bool{"hot": true, "s0": true, "s7": true, "s8": false,
+		"s20": false, "s29": true} {
+		if _, ok := wtl.keys_to_items[key]; ok != present {
+			t.Errorf("Expected item with key '%s' to be in the cache: %t", key, present)
+			t.FailNow()
+		}
+	}
+
+	if evictions := wtl.Stats().Evictions; evictions != 21 {
+		t.Errorf("Expected 21 evictions, got %d", evictions)
+		t.FailNow()
+	}
+
+	// a key set more often than 's0' is admitted in its place
+	wtl.Set("new", 0)
+	wtl.Set("new", 0)
+	wtl.Set("new", 0)
+	wtl.Set("push", 0)
+
+	for key, present := range map[string]bool{"new": true, "s0": false, "push": true} {
+		if _, ok := wtl.keys_to_items[key]; ok != present {
+			t.Errorf("Expected item with key '%s' to be in the cache: %t", key, present)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a W-TinyLFU cache keeps an item of its window that grows,
+// even once it is pushed out of the window to make room for itself.
+func Test_WTinyLFUGrowingUpdate(t *testing.T) {
+
+	// a window of 1 byte and a main region of 99
+	wtl := mustCache(NewWTinyLFUCache[string, int](100, WithByteCapacity()))
+
+	// the other items are used more often, so 'grow' would lose
+	// admission to the main region if it were not being updated
+	for i := 0; i < 9; i++ {
+		wtl.Set(fmt.Sprintf("%d", i), i, WithSize(10))
+		for j := 0; j < 3; j++ {
+			wtl.Get(fmt.Sprintf("%d", i))
+		}
+	}
+	wtl.Set("grow", 0, WithSize(1))
+
+	set_success, err := wtl.Set("grow", 1, WithSize(30))
+	if err != nil || !set_success {
+		t.Errorf("Failed to update binding with key: grow")
+		t.FailNow()
+	}
+
+	if value, ok := wtl.Get("grow"); !ok || value != 1 {
+		t.Errorf("Expected value 1 for key grow, got %d", value)
+		t.FailNow()
+	}
+}
+
+// Tests that a W-TinyLFU cache in byte capacity mode makes room for an
+// item of the main region that grows when it is the only item there,
+// by evicting from the window.
+func Test_WTinyLFUGrowingMainItem(t *testing.T) {
+
+	// a window of 1 byte and a main region of 1 byte
+	wtl := mustCache(NewWTinyLFUCache[string, int](2, WithByteCapacity()))
+
+	// '6' moves from the window to probation and is hit there,
+	// moving it to protected, while '7' stays in the window
+	wtl.Set("6", 6, WithSize(1))
+	wtl.Set("7", 7, WithSize(1))
+	wtl.Get("6")
+
+	set_success, err := wtl.Set("6", 60, WithSize(2))
+	if err != nil || !set_success {
+		t.Errorf("Failed to update binding with key: 6")
+		t.FailNow()
+	}
+
+	if used := wtl.window.used + wtl.probation.used + wtl.protected.used; used > 2 {
+		t.Errorf("Expected at most 2 bytes used, got %d", used)
+		t.FailNow()
+	}
+
+	if value, ok := wtl.Get("6"); !ok || value != 60 || wtl.Contains("7") {
+		t.Errorf("Expected value 60 for key 6 and key 7 to be evicted, got %d", value)
+		t.FailNow()
+	}
+}
+
+// Tests that a W-TinyLFU cache in byte capacity mode sizes its sketch
+// by the number of items it holds instead of by its max capacity.
+func Test_WTinyLFUByteSketch(t *testing.T) {
+	wtl := mustCache(NewWTinyLFUCache[string, int](1<<30, WithByteCapacity()))
+
+	for i := 0; i < 100; i++ {
+		wtl.Set(fmt.Sprintf("%d", i), i, WithSize(1<<20))
+	}
+
+	if wtl.sketch.width != sketchWidth(100) || wtl.sketch.sample_size != 10*100 {
+		t.Errorf("Expected a sketch sized for 100 keys, got a width of %d and a sample size of %d",
+			wtl.sketch.width, wtl.sketch.sample_size)
+		t.FailNow()
+	}
+}
+
+// Tests that a frequency sketch counts accesses up to 15 and halves
+// its counters once its sample is full.
+func Test_FrequencySketch(t *testing.T) {
+	sketch := newFrequencySketch(16)
+
+	for i := 0; i < 20; i++ {
+		sketch.increment(keyHash("a"))
+	}
+
+	if sketch.estimate(keyHash("a")) != 15 || sketch.estimate(keyHash("b")) != 0 {
+		t.Errorf("Expected estimates of 15 and 0, got %d and %d",
+			sketch.estimate(keyHash("a")), sketch.estimate(keyHash("b")))
+		t.FailNow()
+	}
+
+	sketch.reset()
+
+	if sketch.estimate(keyHash("a")) != 7 {
+		t.Errorf("Expected an estimate of 7 after a reset, got %d", sketch.estimate(keyHash("a")))
+		t.FailNow()
+	}
+
+	// the sample of 160 accesses fills up, halving every counter again
+	for i := 0; i < 150; i++ {
+		sketch.increment(keyHash("c"))
+	}
+
+	if sketch.estimate(keyHash("a")) != 3 || sketch.estimate(keyHash("c")) != 7 {
+		t.Errorf("Expected estimates of 3 and 7, got %d and %d",
+			sketch.estimate(keyHash("a")), sketch.estimate(keyHash("c")))
+		t.FailNow()
+	}
+
+	if keyHash(1) == keyHash(2) || keyHash("a") != keyHash("a") {
+		t.Errorf("Expected hashes of different keys to differ and of the same key to match")
+		t.FailNow()
+	}
+
+	// string and integer keys are hashed without allocating
+	if allocations := testing.AllocsPerRun(10, func() { keyHash(1000); keyHash("key") }); allocations != 0 {
+		t.Errorf("Expected no allocations to hash keys, got %g", allocations)
+		t.FailNow()
+	}
+
+	if keyHash(struct{ a, b int }{1, 2}) != keyHash(struct{ a, b int }{1, 2}) {
+		t.Errorf("Expected hashes of the same key of another type to match")
+		t.FailNow()
+	}
+}
+
+/*********************************************************************/
+
+// Tests the creation of a SIEVE cache. Then performs set and get operations.
+func Test_CreateSIEVE(t *testing.T) {
+	max_capacity := 50
+	sieve := mustCache(NewSIEVECache[string, int](max_capacity))
+	for i := 0; i < max_capacity; i++ {
+		key := fmt.Sprintf("%d", i)
+		set_success, err := sieve.Set(key, i)
+		if err != nil || !set_success {
+			t.Errorf("Failed to set binding with key: %s", key)
+			t.FailNow()
+		}
+		value, get_success := sieve.Get(key)
+		if !get_success {
+			t.Errorf("Failed to get binding with key: %s", key)
+			t.FailNow()
+		}
+		if value != i {
+			t.Errorf("Expected value %d for key %s, got %d", i, key, value)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a SIEVE cache keeps visited items and that its hand moves
+// on from the last item it evicted instead of starting over.
+func Test_SIEVEEviction(t *testing.T) {
+	sieve := mustCache(NewSIEVECache[string, int](3))
+
+	sieve.Set("a", 0)
+	sieve.Set("b", 0)
+	sieve.Set("c", 0)
+	sieve.Get("a")
+
+	// the hand clears 'a' and evicts 'b', then evicts 'c' from where it stopped
+	sieve.Set("d", 0)
+	sieve.Set("e", 0)
+
+	for key, present := range map[string]bool{"a": true, "b": false, "c": false, "d": true, "e": true} {
+		if sieve.Contains(key) != present {
+			t.Errorf("Expected item with key '%s' to be in the cache: %t", key, present)
+			t.FailNow()
+		}
+	}
+
+	// 'a' was cleared, so only a new visit keeps it, and the hand
+	// passes 'd' before it comes back around
+	sieve.Get("d")
+	sieve.Set("f", 0)
+
+	for key, present := range map[string]bool{"a": true, "d": true, "e": false, "f": true} {
+		if sieve.Contains(key) != present {
+			t.Errorf("Expected item with key '%s' to be in the cache: %t", key, present)
+			t.FailNow()
+		}
+	}
+
+	if evictions := sieve.Stats().Evictions; evictions != 3 {
+		t.Errorf("Expected 3 evictions, got %d", evictions)
+		t.FailNow()
+	}
+}
+
+/*********************************************************************/
+
+// Tests the creation of a S3-FIFO cache. Then performs set and get operations.
+func Test_CreateS3FIFO(t *testing.T) {
+	max_capacity := 50
+	s3fifo := mustCache(NewS3FIFOCache[string, int](max_capacity))
+	for i := 0; i < max_capacity; i++ {
+		key := fmt.Sprintf("%d", i)
+		set_success, err := s3fifo.Set(key, i)
+		if err != nil || !set_success {
+			t.Errorf("Failed to set binding with key: %s", key)
+			t.FailNow()
+		}
+		value, get_success := s3fifo.Get(key)
+		if !get_success {
+			t.Errorf("Failed to get binding with key: %s", key)
+			t.FailNow()
+		}
+		if value != i {
+			t.Errorf("Expected value %d for key %s, got %d", i, key, value)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a S3-FIFO cache moves items used in its small queue to its
+// main queue, evicts the others to its ghost queue, and sets keys
+// remembered in the ghost queue straight in the main queue.
+func Test_S3FIFOEviction(t *testing.T) {
+
+	// a small queue of 1 item and a main queue of 9
+	s3fifo := mustCache(NewS3FIFOCache[string, int](10))
+
+	for i := 0; i < 10; i++ {
+		s3fifo.Set(fmt.Sprintf("k%d", i), i)
+	}
+	s3fifo.Get("k0")
+
+	// 'k0' was used, so it moves to main and 'k1' is evicted instead
+	s3fifo.Set("x", 0)
+
+	if s3fifo.main.linked_list.Len() != 1 || s3fifo.Contains("k1") {
+		t.Errorf("Expected 'k0' in the main queue and 'k1' to be evicted")
+		t.FailNow()
+	}
+
+	// keys in the ghost queue are misses and can not be deleted
+	if _, ok := s3fifo.Get("k1"); ok || s3fifo.Delete("k1") {
+		t.Errorf("Expected 'k1' to be a miss that can not be deleted")
+		t.FailNow()
+	}
+
+	// 'k1' is remembered, so it goes straight to main and 'k2' is evicted
+	s3fifo.Set("k1", 1)
+
+	if s3fifo.main.linked_list.Len() != 2 || !s3fifo.Contains("k1") || s3fifo.Contains("k2") {
+		t.Errorf("Expected 'k1' in the main queue and 'k2' to be evicted")
+		t.FailNow()
+	}
+
+	if evictions := s3fifo.Stats().Evictions; evictions != 2 {
+		t.Errorf("Expected 2 evictions, got %d", evictions)
+		t.FailNow()
+	}
+}
+
+/*********************************************************************/
+
+// Tests the creation of a CLOCK cache. Then performs set and get operations.
+func Test_CreateCLOCK(t *testing.T) {
+	max_capacity := 50
+	cc := mustCache(NewCLOCKCache[string, int](max_capacity))
+	for i := 0; i < max_capacity; i++ {
+		key := fmt.Sprintf("%d", i)
+		set_success, err := cc.Set(key, i)
+		if err != nil || !set_success {
+			t.Errorf("Failed to set binding with key: %s", key)
+			t.FailNow()
+		}
+		value, get_success := cc.Get(key)
+		if !get_success {
+			t.Errorf("Failed to get binding with key: %s", key)
+			t.FailNow()
+		}
+		if value != i {
+			t.Errorf("Expected value %d for key %s, got %d", i, key, value)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a CLOCK cache gives referenced items a second chance and
+// sets new items just behind its hand, so that the hand reaches them last.
+func Test_CLOCKEviction(t *testing.T) {
+	cc := mustCache(NewCLOCKCache[string, int](3))
+
+	cc.Set("a", 0)
+	cc.Set("b", 0)
+	cc.Set("c", 0)
+	cc.Get("a")
+
+	// the hand clears 'a' and evicts 'b', then evicts 'c' from where it stopped
+	cc.Set("d", 0)
+	cc.Set("e", 0)
+
+	for key, present := range map[string]bool{"a": true, "b": false, "c": false, "d": true, "e": true} {
+		if cc.Contains(key) != present {
+			t.Errorf("Expected item with key '%s' to be in the cache: %t", key, present)
+			t.FailNow()
+		}
+	}
+
+	// the hand reaches 'a' before the newer 'd' and 'e',
+	// and 'a' was not referenced since the hand cleared it
+	cc.Get("d")
+	cc.Set("f", 0)
+
+	for key, present := range map[string]bool{"a": false, "d": true, "e": true, "f": true} {
+		if cc.Contains(key) != present {
+			t.Errorf("Expected item with key '%s' to be in the cache: %t", key, present)
+			t.FailNow()
+		}
+	}
+
+	if evictions := cc.Stats().Evictions; evictions != 3 {
+		t.Errorf("Expected 3 evictions, got %d", evictions)
+		t.FailNow()
+	}
+}
+
+/*********************************************************************/
+
+// Tests the creation of a CLOCK-Pro cache. Then performs set and get operations.
+func Test_CreateCLOCKPro(t *testing.T) {
+	max_capacity := 50
+	pro := mustCache(NewCLOCKProCache[string, int](max_capacity))
+	for i := 0; i < max_capacity; i++ {
+		key := fmt.Sprintf("%d", i)
+		set_success, err := pro.Set(key, i)
+		if err != nil || !set_success {
+			t.Errorf("Failed to set binding with key: %s", key)
+			t.FailNow()
+		}
+		value, get_success := pro.Get(key)
+		if !get_success {
+			t.Errorf("Failed to get binding with key: %s", key)
+			t.FailNow()
+		}
+		if value != i {
+			t.Errorf("Expected value %d for key %s, got %d", i, key, value)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a CLOCK-Pro cache leaves test keys behind evicted cold items,
+// makes referenced cold items hot once the hot items have a share of the
+// cache, and sets a test key that is set again as a hot item.
+func Test_CLOCKProEviction(t *testing.T) {
+	pro := mustCache(NewCLOCKProCache[string, int](4))
+
+	// the fifth eviction leaves more test keys than fit in the
+	// cache, so the oldest is forgotten and the cold share shrinks
+	for i := 0; i < 9; i++ {
+		pro.Set(fmt.Sprintf("k%d", i), i)
+	}
+
+	if pro.cold_capacity != 3 || pro.Stats().Evictions != 5 {
+		t.Errorf("Expected a cold capacity of 3 and 5 evictions, got %d and %d",
+			pro.cold_capacity, pro.Stats().Evictions)
+		t.FailNow()
+	}
+
+	// test keys are misses and can not be deleted
+	if _, ok := pro.Get("k4"); ok || pro.Delete("k4") || pro.Contains("k4") {
+		t.Errorf("Expected 'k4' to be a miss that can not be deleted")
+		t.FailNow()
+	}
+
+	// the cold hand passes 'k6' while it is referenced, so it becomes hot
+	pro.Get("k6")
+	pro.Set("x", 0)
+	pro.Set("y", 0)
+
+	if element := pro.keys_to_items["k6"]; element.Value.(*CLOCKProCacheItem[string, int]).status != clockpro_hot {
+		t.Errorf("Item with key 'k6' should be hot.")
+		t.FailNow()
+	}
+
+	// 'k5' was evicted too early, so it is hot at once and the cold share grows
+	cold_capacity := pro.cold_capacity
+	pro.Set("k5", 5)
+
+	if element := pro.keys_to_items["k5"]; element.Value.(*CLOCKProCacheItem[string, int]).status != clockpro_hot {
+		t.Errorf("Item with key 'k5' should be hot.")
+		t.FailNow()
+	}
+
+	if pro.cold_capacity != cold_capacity+1 {
+		t.Errorf("Expected a cold capacity of %d, got %d", cold_capacity+1, pro.cold_capacity)
+		t.FailNow()
+	}
+
+	if value, ok := pro.Get("k5"); !ok || value != 5 {
+		t.Errorf("Expected value 5 for key k5, got %d", value)
+		t.FailNow()
+	}
+}
+
+/*********************************************************************/
+
+// Tests that setting an existing key replaces its value in every cache.
+func Test_UpdateValue(t *testing.T) {
+	caches := map[string]Cache[string, string]{
+		"FIFO":       mustCache(NewFIFOCache[string, string](2)),
+		"LRU":        mustCache(NewLRUCache[string, string](2)),
+		"LFU":        mustCache(NewLFUCache[string, string](2)),
+		"ARC":        mustCache(NewARCCache[string, string](2)),
+		"W-TINYLFU":  mustCache(NewWTinyLFUCache[string, string](2)),
+		"SIEVE":      mustCache(NewSIEVECache[string, string](2)),
+		"S3-FIFO":    mustCache(NewS3FIFOCache[string, string](2)),
+		"CLOCK":      mustCache(NewCLOCKCache[string, string](2)),
+		"CLOCK-PRO":  mustCache(NewCLOCKProCache[string, string](2)),
+		"HYPERBOLIC": mustCache(NewHyperbolicCache[string, string](2, 2)),
+	}
+
+	for name, cache := range caches {
+		cache.Set("a", "first")
+		cache.Set("a", "second")
+
+		value, ok := cache.Get("a")
+		if !ok || value != "second" {
+			t.Errorf("%s: expected value 'second' for key 'a', got '%s'", name, value)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a KeyCache only reports whether keys are present.
+func Test_KeyCache(t *testing.T) {
+	cache := NewKeyCache(mustCache(NewLRUCache[string, struct{}](1)))
+
+	if ok, err := cache.Set("a"); err != nil || !ok {
+		t.Errorf("Failed to set binding with key: %s", "a")
+		t.FailNow()
+	}
+
+	if !cache.Get("a") {
+		t.Errorf("Item with key 'a' should be in the cache!")
+		t.FailNow()
+	}
+
+	cache.Set("b")
+
+	if cache.Get("a") {
+		t.Errorf("Item with key 'a' should have been evicted.")
+		t.FailNow()
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d hits and %d misses",
+			stats.Hits, stats.Misses)
+		t.FailNow()
+	}
+}
+
+// Tests that deleting an item removes it from every cache and frees up
+// room for another item without evicting anything.
+func Test_Delete(t *testing.T) {
+	caches := map[string]Cache[string, int]{
+		"FIFO":       mustCache(NewFIFOCache[string, int](2)),
+		"LRU":        mustCache(NewLRUCache[string, int](2)),
+		"LFU":        mustCache(NewLFUCache[string, int](2)),
+		"ARC":        mustCache(NewARCCache[string, int](2)),
+		"W-TINYLFU":  mustCache(NewWTinyLFUCache[string, int](2)),
+		"SIEVE":      mustCache(NewSIEVECache[string, int](2)),
+		"S3-FIFO":    mustCache(NewS3FIFOCache[string, int](2)),
+		"CLOCK":      mustCache(NewCLOCKCache[string, int](2)),
+		"CLOCK-PRO":  mustCache(NewCLOCKProCache[string, int](2)),
+		"HYPERBOLIC": mustCache(NewHyperbolicCache[string, int](2, 2)),
+	}
+
+	for name, cache := range caches {
+		cache.Set("a", 0)
+		cache.Set("b", 1)
+
+		if !cache.Delete("a") {
+			t.Errorf("%s: failed to delete binding with key: %s", name, "a")
+			t.FailNow()
+		}
+
+		if cache.Delete("a") {
+			t.Errorf("%s: key 'a' should have already been deleted.", name)
+			t.FailNow()
+		}
+
+		if _, ok := cache.Get("a"); ok {
+			t.Errorf("%s: item with key 'a' should have been deleted.", name)
+			t.FailNow()
+		}
+
+		// there is room for another item, so nothing should be evicted
+		cache.Set("c", 2)
+
+		if _, ok := cache.Get("b"); !ok {
+			t.Errorf("%s: item with key 'b' should be in the cache!", name)
+			t.FailNow()
+		}
+
+		if _, ok := cache.Get("c"); !ok {
+			t.Errorf("%s: item with key 'c' should be in the cache!", name)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that deleting from a LFU cache drops access count nodes that
+// no longer have any items.
+func Test_LFUDeleteAccessNodes(t *testing.T) {
+	lfu := mustCache(NewLFUCache[string, int](3))
+
+	lfu.Set("a", 0)
+	lfu.Set("b", 0)
+	lfu.Get("b")
+
+	if lfu.access_counts.Len() != 2 {
+		t.Errorf("Expected 2 access count nodes, got %d", lfu.access_counts.Len())
+		t.FailNow()
+	}
+
+	lfu.Delete("b")
+
+	if lfu.access_counts.Len() != 1 || lfu.size != 1 {
+		t.Errorf("Expected 1 access count node and 1 item, got %d and %d",
+			lfu.access_counts.Len(), lfu.size)
+		t.FailNow()
+	}
+
+	lfu.Delete("a")
+
+	if lfu.access_counts.Len() != 0 || lfu.size != 0 {
+		t.Errorf("Expected an empty cache, got %d access count nodes and %d items",
+			lfu.access_counts.Len(), lfu.size)
+		t.FailNow()
+	}
+}
+
+// Tests that a LFU cache evicts a single item per insertion, breaking
+// ties between items with the same access count by least recent access.
+func Test_LFUSingleVictim(t *testing.T) {
+	lfu := mustCache(NewLFUCache[string, int](3))
+
+	lfu.Set("a", 0)
+	lfu.Set("b", 0)
+	lfu.Set("c", 0)
+	lfu.Set("d", 0)
+
+	for key, present := range map[string]bool{"a": false, "b": true, "c": true, "d": true} {
+		if _, ok := lfu.keys_to_items[key]; ok != present {
+			t.Errorf("Expected item with key '%s' to be in the cache: %t", key, present)
+			t.FailNow()
+		}
+	}
+
+	// every item now has the same access count, 'c' accessed least recently
+	lfu.Get("c")
+	lfu.Get("b")
+	lfu.Get("d")
+	lfu.Set("e", 0)
+
+	for key, present := range map[string]bool{"b": true, "c": false, "d": true, "e": true} {
+		if _, ok := lfu.keys_to_items[key]; ok != present {
+			t.Errorf("Expected item with key '%s' to be in the cache: %t", key, present)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that halving access counts rounds them up and merges access
+// count nodes that end up with the same access count.
+func Test_LFUHalve(t *testing.T) {
+	lfu := mustCache(NewLFUCache[string, int](4))
+
+	// give 'a' through 'd' access counts 1 through 4
+	for i, key := range []string{"a", "b", "c", "d"} {
+		lfu.Set(key, 0)
+		for j := 0; j < i; j++ {
+			lfu.Get(key)
+		}
+	}
+
+	lfu.halve()
+
+	if lfu.access_counts.Len() != 2 {
+		t.Errorf("Expected 2 access count nodes, got %d", lfu.access_counts.Len())
+		t.FailNow()
+	}
+
+	for i, keys := range [][]string{{"a", "b"}, {"c", "d"}} {
+		for _, key := range keys {
+			node := lfu.keys_to_items[key].accessParent.Value.(*AccessNode[string, int])
+			if node.access_count != i+1 {
+				t.Errorf("Expected access count %d for key %s, got %d", i+1, key, node.access_count)
+				t.FailNow()
+			}
+		}
+	}
+
+	// 'a' had the smaller access count, so it is evicted first
+	lfu.Set("e", 0)
+	if _, ok := lfu.keys_to_items["a"]; ok {
+		t.Errorf("Item with key 'a' should have been evicted.")
+		t.FailNow()
+	}
+}
+
+// Tests that LFU caches with aging eventually evict an item that was
+// popular long ago, while a plain LFU cache holds on to it forever.
+func Test_LFUAging(t *testing.T) {
+	if _, err := NewLFUCache[string, int](2, WithHalvingPeriod(-1)); !errors.Is(err, ErrInvalidAging) ||
+		!errors.Is(err, ErrNegativeValue) {
+		t.Errorf("Expected a negative halving period error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewLFUCache[string, int](2, WithHalvingPeriod(4), WithDynamicAging()); !errors.Is(err, ErrInvalidAging) {
+		t.Errorf("Expected an invalid aging error, got: %v", err)
+		t.FailNow()
+	}
+
+	caches := map[string]*LFUCache[string, int]{
+		"LFU":         mustCache(NewLFUCache[string, int](2)),
+		"LFU-HALVING": mustCache(NewLFUCache[string, int](2, WithHalvingPeriod(4))),
+		"LFU-DA":      mustCache(NewLFUCache[string, int](2, WithDynamicAging())),
+	}
+
+	for name, lfu := range caches {
+
+		// 'a' is popular at first
+		lfu.Set("a", 0)
+		for i := 0; i < 7; i++ {
+			lfu.Get("a")
+		}
+
+		// then popularity shifts to a stream of new keys
+		for i := 0; i < 20; i++ {
+			key := fmt.Sprintf("%d", i)
+			lfu.Set(key, 0)
+			lfu.Get(key)
+			lfu.Get(key)
+		}
+
+		_, ok := lfu.keys_to_items["a"]
+		if name == "LFU" && !ok {
+			t.Errorf("%s: item with key 'a' should be in the cache!", name)
+			t.FailNow()
+		}
+		if name != "LFU" && ok {
+			t.Errorf("%s: item with key 'a' should have been evicted.", name)
+			t.FailNow()
+		}
+	}
+
+	if caches["LFU-DA"].age == 0 {
+		t.Errorf("Expected the LFU-DA cache to have aged.")
+		t.FailNow()
+	}
+}
+
+// Tests that every cache counts the items it evicts to make room, but
+// not the items that are deleted.
+func Test_Evictions(t *testing.T) {
+	caches := map[string]Cache[string, int]{
+		"FIFO":       mustCache(NewFIFOCache[string, int](2)),
+		"LRU":        mustCache(NewLRUCache[string, int](2)),
+		"LFU":        mustCache(NewLFUCache[string, int](2)),
+		"ARC":        mustCache(NewARCCache[string, int](2)),
+		"W-TINYLFU":  mustCache(NewWTinyLFUCache[string, int](2)),
+		"SIEVE":      mustCache(NewSIEVECache[string, int](2)),
+		"S3-FIFO":    mustCache(NewS3FIFOCache[string, int](2)),
+		"CLOCK":      mustCache(NewCLOCKCache[string, int](2)),
+		"CLOCK-PRO":  mustCache(NewCLOCKProCache[string, int](2)),
+		"HYPERBOLIC": mustCache(NewHyperbolicCache[string, int](2, 2)),
+	}
+
+	for name, cache := range caches {
+		for _, key := range []string{"a", "b", "c", "d"} {
+			cache.Set(key, 0)
+		}
+
+		for _, key := range []string{"a", "b", "c", "d"} {
+			cache.Delete(key)
+		}
+
+		if evictions := cache.Stats().Evictions; evictions != 2 {
+			t.Errorf("%s: expected 2 evictions, got %d", name, evictions)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that caches can not be created with unusable capacities or sample sizes.
+func Test_InvalidConstructors(t *testing.T) {
+	if _, err := NewFIFOCache[string, int](-1); !errors.Is(err, ErrInvalidCapacity) ||
+		!errors.Is(err, ErrNegativeValue) {
+		t.Errorf("Expected a negative capacity error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewLRUCache[string, int](-1); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("Expected an invalid capacity error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewLFUCache[string, int](-1); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("Expected an invalid capacity error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewARCCache[string, int](-1); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("Expected an invalid capacity error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewWTinyLFUCache[string, int](-1); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("Expected an invalid capacity error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewSIEVECache[string, int](-1); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("Expected an invalid capacity error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewS3FIFOCache[string, int](-1); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("Expected an invalid capacity error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewCLOCKCache[string, int](-1); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("Expected an invalid capacity error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewCLOCKProCache[string, int](-1); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("Expected an invalid capacity error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewHyperbolicCache[string, int](-1, 0); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("Expected an invalid capacity error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewHyperbolicCache[string, int](5, -1); !errors.Is(err, ErrInvalidSampleSize) ||
+		!errors.Is(err, ErrNegativeValue) {
+		t.Errorf("Expected a negative sample size error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewHyperbolicCache[string, int](5, 6); !errors.Is(err, ErrInvalidSampleSize) {
+		t.Errorf("Expected an invalid sample size error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewHyperbolicCache[string, int](5, 0); !errors.Is(err, ErrInvalidSampleSize) {
+		t.Errorf("Expected an invalid sample size error, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// Tests that a hyperbolic cache reports inconsistent state as an error
+// instead of exiting.
+func Test_HyperbolicInconsistentState(t *testing.T) {
+	hyperbolic := mustCache(NewHyperbolicCache[string, int](2, 2))
+
+	hyperbolic.Set("a", 0)
+	hyperbolic.Set("b", 0)
+
+	// claim the cache is full while it is missing an item
+	hyperbolic.items = hyperbolic.items[:1]
+
+	set_success, err := hyperbolic.Set("c", 0)
+	if set_success || !errors.Is(err, ErrInconsistentState) {
+		t.Errorf("Expected an inconsistent state error, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// Tests that a hyperbolic cache in byte capacity mode with a clock in
+// nanoseconds keeps a large, popular item over a large, unpopular one,
+// even once size times time in cache no longer fits in an int.
+func Test_HyperbolicNanosecondClock(t *testing.T) {
+	clock := NewManualClock(0)
+	size := 4 << 20
+	hyperbolic := mustCache(NewHyperbolicCache[string, int](2*size, 2, WithByteCapacity(),
+		WithClock(clock), WithSource(rand.NewSource(0))))
+
+	hyperbolic.Set("popular", 0, WithSize(size))
+	hyperbolic.Set("unpopular", 0, WithSize(size))
+	for i := 0; i < 100; i++ {
+		hyperbolic.Get("popular")
+	}
+
+	// an hour later
+	clock.Advance(3600 * 1000 * 1000 * 1000)
+
+	popular := hyperbolic.keys_to_items["popular"].metadata
+	if priority := HyperbolicPriority(popular, clock.Now()); priority <= 0 {
+		t.Errorf("Expected a positive priority, got %g", priority)
+		t.FailNow()
+	}
+
+	hyperbolic.Set("new", 0, WithSize(size))
+
+	if !hyperbolic.Contains("popular") || hyperbolic.Contains("unpopular") {
+		t.Errorf("Expected 'unpopular' to be evicted instead of 'popular'")
+		t.FailNow()
+	}
+}
+
+// Tests that hyperbolic caches seeded with the same source evict the same items.
+func Test_HyperbolicSeededEviction(t *testing.T) {
+	clock := NewManualClock(0)
+	first := mustCache(NewHyperbolicCache[string, int](10, 3, WithSource(rand.NewSource(316)),
+		WithClock(clock)))
+	second := mustCache(NewHyperbolicCache[string, int](10, 3, WithSource(rand.NewSource(316)),
+		WithClock(clock)))
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("%d", i)
+		clock.Set(i)
+		first.Set(key, i)
+		second.Set(key, i)
+	}
+
+	for key := range first.keys_to_items {
+		if _, ok := second.keys_to_items[key]; !ok {
+			t.Errorf("Item with key '%s' should be in both caches!", key)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that the hyperbolic cache's slice of items stays dense and
+// in sync with its map as items are evicted and deleted.
+func Test_HyperbolicDenseItems(t *testing.T) {
+	hyperbolic := mustCache(NewHyperbolicCache[string, int](8, 4, WithSource(rand.NewSource(0))))
+
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("%d", i)
+		hyperbolic.Set(key, i)
+
+		if i%3 == 0 {
+			hyperbolic.Delete(fmt.Sprintf("%d", i/2))
+		}
+
+		if len(hyperbolic.items) != hyperbolic.size ||
+			len(hyperbolic.keys_to_items) != hyperbolic.size {
+			t.Errorf("Expected %d items, got %d in the slice and %d in the map",
+				hyperbolic.size, len(hyperbolic.items), len(hyperbolic.keys_to_items))
+			t.FailNow()
+		}
+
+		for index, item := range hyperbolic.items {
+			if item.index != index || hyperbolic.keys_to_items[item.key] != item {
+				t.Errorf("Item with key '%s' is out of place", item.key)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+// Tests that retained eviction candidates are carried over between
+// evictions and that they improve the hit ratio of a small sample size
+// on a skewed workload.
+func Test_HyperbolicRetainedCandidates(t *testing.T) {
+	if _, err := NewHyperbolicCache[string, int](5, 2, WithRetainedCandidates(-1)); !errors.Is(err, ErrNegativeValue) {
+		t.Errorf("Expected a negative value error, got: %v", err)
+		t.FailNow()
+	}
+
+	clock := NewManualClock(0)
+	plain := mustCache(NewHyperbolicCache[string, int](100, 2, WithSource(rand.NewSource(316)),
+		WithClock(clock)))
+	retaining := mustCache(NewHyperbolicCache[string, int](100, 2, WithSource(rand.NewSource(316)),
+		WithRetainedCandidates(16), WithClock(clock)))
+
+	zipf := rand.NewZipf(rand.New(rand.NewSource(316)), 1.1, 1, 10000)
+
+	for i := 1; i <= 100000; i++ {
+		key := fmt.Sprintf("%d", zipf.Uint64())
+		clock.Set(i)
+
+		for _, cache := range []*HyperbolicCache[string, int]{plain, retaining} {
+			if _, ok := cache.Get(key); !ok {
+				cache.Set(key, i)
+			}
+		}
+
+		if len(retaining.retained) > 16 {
+			t.Errorf("Expected at most 16 retained candidates, got %d", len(retaining.retained))
+			t.FailNow()
+		}
+	}
+
+	if len(retaining.retained) == 0 {
+		t.Errorf("Expected candidates to be retained between evictions.")
+		t.FailNow()
+	}
+
+	plain_stats, retaining_stats := plain.Stats(), retaining.Stats()
+	t.Logf("hit ratio without retention: %f, with retention: %f",
+		float32(plain_stats.Hits)/float32(plain_stats.Hits+plain_stats.Misses),
+		float32(retaining_stats.Hits)/float32(retaining_stats.Hits+retaining_stats.Misses))
+
+	if retaining_stats.Hits <= plain_stats.Hits {
+		t.Errorf("Expected retaining candidates to improve the hit ratio.")
+		t.FailNow()
+	}
+}
+
+// Tests that retained candidates that were deleted from the cache are
+// dropped instead of being evicted again.
+func Test_HyperbolicRetainedDeleted(t *testing.T) {
+	hyperbolic := mustCache(NewHyperbolicCache[string, int](4, 4, WithRetainedCandidates(3)))
+
+	for i := 0; i < 5; i++ {
+		hyperbolic.Set(fmt.Sprintf("%d", i), i)
+	}
+
+	// delete every retained candidate, then re-insert one of their keys
+	for _, candidate := range hyperbolic.retained {
+		hyperbolic.Delete(candidate.key)
+	}
+	hyperbolic.Set(hyperbolic.retained[0].key, 5)
+
+	for i := 6; i < 10; i++ {
+		key := fmt.Sprintf("%d", i)
+		set_success, err := hyperbolic.Set(key, i)
+		if err != nil || !set_success {
+			t.Errorf("Failed to set binding with key: %s", key)
+			t.FailNow()
+		}
+
+		if len(hyperbolic.keys_to_items) != hyperbolic.size || hyperbolic.size > 4 {
+			t.Errorf("Expected at most 4 items, got %d", hyperbolic.size)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that the hyperbolic cache holds on to expensive items and
+// evicts large items first.
+func Test_HyperbolicCostAware(t *testing.T) {
+	clock := NewManualClock(0)
+	hyperbolic := mustCache(NewHyperbolicCache[string, int](2, 2, WithClock(clock)))
+
+	// without costs, the older item would have the lower priority
+	clock.Set(0)
+	hyperbolic.Set("expensive", 0, WithCost(10))
+	clock.Set(1)
+	hyperbolic.Set("cheap", 0)
+	clock.Set(2)
+	hyperbolic.Set("A", 0)
+
+	if _, ok := hyperbolic.Get("cheap"); ok {
+		t.Errorf("Item with key 'cheap' should have been evicted.")
+		t.FailNow()
+	}
+
+	if _, ok := hyperbolic.Get("expensive"); !ok {
+		t.Errorf("Item with key 'expensive' should be in the cache!")
+		t.FailNow()
+	}
+
+	hyperbolic = mustCache(NewHyperbolicCache[string, int](2, 2, WithClock(clock)))
+
+	// without sizes, the older item would have the lower priority
+	clock.Set(0)
+	hyperbolic.Set("small", 0)
+	clock.Set(1)
+	hyperbolic.Set("large", 0, WithSize(10))
+	clock.Set(2)
+	hyperbolic.Set("A", 0)
+
+	if _, ok := hyperbolic.Get("large"); ok {
+		t.Errorf("Item with key 'large' should have been evicted.")
+		t.FailNow()
+	}
+
+	if _, ok := hyperbolic.Get("small"); !ok {
+		t.Errorf("Item with key 'small' should be in the cache!")
+		t.FailNow()
+	}
+}
+
+// Tests that every cache rejects invalid item options.
+func Test_InvalidItemOptions(t *testing.T) {
+	caches := map[string]Cache[string, int]{
+		"FIFO":       mustCache(NewFIFOCache[string, int](2)),
+		"LRU":        mustCache(NewLRUCache[string, int](2)),
+		"LFU":        mustCache(NewLFUCache[string, int](2)),
+		"ARC":        mustCache(NewARCCache[string, int](2)),
+		"W-TINYLFU":  mustCache(NewWTinyLFUCache[string, int](2)),
+		"SIEVE":      mustCache(NewSIEVECache[string, int](2)),
+		"S3-FIFO":    mustCache(NewS3FIFOCache[string, int](2)),
+		"CLOCK":      mustCache(NewCLOCKCache[string, int](2)),
+		"CLOCK-PRO":  mustCache(NewCLOCKProCache[string, int](2)),
+		"HYPERBOLIC": mustCache(NewHyperbolicCache[string, int](2, 2)),
+	}
+
+	for name, cache := range caches {
+		for _, opt := range []ItemOption{WithCost(-1), WithCost(0), WithCost(math.NaN()),
+			WithSize(-1), WithSize(0), WithTTL(-1)} {
+			set_success, err := cache.Set("a", 0, opt)
+			if set_success || !errors.Is(err, ErrInvalidItemOption) {
+				t.Errorf("%s: expected an invalid item option error, got: %v", name, err)
+				t.FailNow()
+			}
+		}
+
+		if _, ok := cache.Get("a"); ok {
+			t.Errorf("%s: item with key 'a' should not have been set.", name)
+			t.FailNow()
+		}
+	}
+}
+
+/*********************************************************************/
+
+// Tests that the hyperbolic priority of an item inserted at the current
+// time is finite, and the same as if it had been in the cache for 1 tick.
+func Test_HyperbolicPriorityAtInsertTime(t *testing.T) {
+	item := ItemMetadata{AccessCount: 2, InsertTime: 5, LastAccessTime: 5, Size: 1, Cost: 3}
+
+	if priority := HyperbolicPriority(item, 5); priority != 6 || priority != HyperbolicPriority(item, 6) {
+		t.Errorf("Expected a priority of 6 at insert time, got %g", priority)
+		t.FailNow()
+	}
+}
+
+// Tests that a sampled cache can not be created without a priority function.
+func Test_SampledNilPriority(t *testing.T) {
+	if _, err := NewSampledCache[string, int](5, 5, nil); !errors.Is(err, ErrInvalidPriority) {
+		t.Errorf("Expected an invalid priority error, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// Tests that a sampled cache with the LRU priority evicts the least
+// recently used item when it samples every item.
+func Test_SampledLRU(t *testing.T) {
+	clock := NewManualClock(0)
+	sampled := mustCache(NewSampledCache[string, int](3, 3, LRUPriority, WithClock(clock)))
+
+	clock.Set(0)
+	sampled.Set("a", 0)
+	clock.Set(1)
+	sampled.Set("b", 0)
+	clock.Set(2)
+	sampled.Set("c", 0)
+	clock.Set(3)
+	sampled.Set("a", 0)
+	clock.Set(4)
+	sampled.Set("d", 0)
+
+	if _, ok := sampled.Get("b"); ok {
+		t.Errorf("Item with key 'b' should have been evicted.")
+		t.FailNow()
+	}
+}
+
+// Tests that a sampled cache with the LFU priority evicts the least
+// frequently used item when it samples every item.
+func Test_SampledLFU(t *testing.T) {
+	sampled := mustCache(NewSampledCache[string, int](3, 3, LFUPriority))
+
+	sampled.Set("a", 0)
+	sampled.Set("b", 0)
+	sampled.Set("c", 0)
+	sampled.Get("a")
+	sampled.Get("c")
+	sampled.Set("d", 0)
+
+	if _, ok := sampled.Get("b"); ok {
+		t.Errorf("Item with key 'b' should have been evicted.")
+		t.FailNow()
+	}
+}
+
+// Tests that a sampled cache with the GDSF priority evicts large items
+// first and inflates the priority of items by that of the evicted item.
+func Test_SampledGDSF(t *testing.T) {
+	sampled := mustCache(NewSampledCache[string, int](2, 2, GDSFPriority))
+
+	sampled.Set("small", 0)
+	sampled.Set("large", 0, WithSize(4))
+	sampled.Set("A", 0)
+
+	if _, ok := sampled.Get("large"); ok {
+		t.Errorf("Item with key 'large' should have been evicted.")
+		t.FailNow()
+	}
+
+	if inflation := sampled.keys_to_items["A"].metadata.Inflation; inflation != 0.25 {
+		t.Errorf("Expected item with key 'A' to be inflated by 0.25, got %f", inflation)
+		t.FailNow()
+	}
+}
+
+// Tests that a sampled cache evicts items by a user-supplied priority.
+func Test_SampledCustomPriority(t *testing.T) {
+
+	// evict the most recently inserted item
+	newest_first := func(item ItemMetadata, now int) float64 {
+		return -float64(item.InsertTime)
+	}
+
+	clock := NewManualClock(0)
+	sampled := mustCache(NewSampledCache[string, int](3, 3, newest_first, WithClock(clock)))
+
+	clock.Set(0)
+	sampled.Set("a", 0)
+	clock.Set(1)
+	sampled.Set("b", 0)
+	clock.Set(2)
+	sampled.Set("c", 0)
+	clock.Set(3)
+	sampled.Set("d", 0)
+
+	if _, ok := sampled.Get("c"); ok {
+		t.Errorf("Item with key 'c' should have been evicted.")
+		t.FailNow()
+	}
+
+	if _, ok := sampled.Get("a"); !ok {
+		t.Errorf("Item with key 'a' should be in the cache!")
+		t.FailNow()
+	}
+}
+
+/*********************************************************************/
+
+// newByteCaches returns one cache of every policy with a byte capacity.
+func newByteCaches(max_capacity int) map[string]Cache[string, int] {
+	return map[string]Cache[string, int]{
+		"FIFO":       mustCache(NewFIFOCache[string, int](max_capacity, WithByteCapacity())),
+		"LRU":        mustCache(NewLRUCache[string, int](max_capacity, WithByteCapacity())),
+		"LFU":        mustCache(NewLFUCache[string, int](max_capacity, WithByteCapacity())),
+		"ARC":        mustCache(NewARCCache[string, int](max_capacity, WithByteCapacity())),
+		"W-TINYLFU":  mustCache(NewWTinyLFUCache[string, int](max_capacity, WithByteCapacity())),
+		"SIEVE":      mustCache(NewSIEVECache[string, int](max_capacity, WithByteCapacity())),
+		"S3-FIFO":    mustCache(NewS3FIFOCache[string, int](max_capacity, WithByteCapacity())),
+		"CLOCK":      mustCache(NewCLOCKCache[string, int](max_capacity, WithByteCapacity())),
+		"CLOCK-PRO":  mustCache(NewCLOCKProCache[string, int](max_capacity, WithByteCapacity())),
+		"HYPERBOLIC": mustCache(NewHyperbolicCache[string, int](max_capacity, 4, WithByteCapacity())),
+	}
+}
+
+// Tests that caches with a byte capacity evict items until a new item fits.
+func Test_ByteCapacity(t *testing.T) {
+	for name, cache := range newByteCaches(10) {
+		for i, key := range []string{"a", "b", "c"} {
+			set_success, err := cache.Set(key, i, WithSize(4))
+			if err != nil || !set_success {
+				t.Errorf("%s: failed to set binding with key: %s", name, key)
+				t.FailNow()
+			}
+		}
+
+		// only two items of size 4 fit in 10 bytes
+		present := 0
+		for _, key := range []string{"a", "b", "c"} {
+			if _, ok := cache.Get(key); ok {
+				present++
+			}
+		}
+		if present != 2 {
+			t.Errorf("%s: expected 2 items in the cache, got %d", name, present)
+			t.FailNow()
+		}
+
+		// W-TinyLFU refuses to admit an item that was accessed less
+		// often than the items it would evict, which is tested above
+		if name == "W-TINYLFU" {
+			continue
+		}
+
+		// an item of size 9 only fits once every other item is evicted
+		cache.Set("d", 3, WithSize(9))
+		for _, key := range []string{"a", "b", "c"} {
+			if _, ok := cache.Get(key); ok {
+				t.Errorf("%s: item with key '%s' should have been evicted.", name, key)
+				t.FailNow()
+			}
+		}
+
+		if _, ok := cache.Get("d"); !ok {
+			t.Errorf("%s: item with key 'd' should be in the cache!", name)
+			t.FailNow()
+		}
+
+		// an item larger than the cache never fits
+		set_success, err := cache.Set("e", 4, WithSize(11))
+		if err != nil || set_success {
+			t.Errorf("%s: item with key 'e' should not fit in the cache.", name)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that caches with a byte capacity make room when an existing item
+// grows, without evicting the item itself.
+func Test_ByteCapacityUpdate(t *testing.T) {
+	for name, cache := range newByteCaches(6) {
+		cache.Set("a", 0, WithSize(2))
+		cache.Set("b", 0, WithSize(2))
+		cache.Set("c", 0, WithSize(2))
+
+		set_success, err := cache.Set("a", 1, WithSize(5))
+		if err != nil || !set_success {
+			t.Errorf("%s: failed to update binding with key: %s", name, "a")
+			t.FailNow()
+		}
+
+		if value, ok := cache.Get("a"); !ok || value != 1 {
+			t.Errorf("%s: item with key 'a' should have been updated.", name)
+			t.FailNow()
+		}
+
+		for _, key := range []string{"b", "c"} {
+			if _, ok := cache.Get(key); ok {
+				t.Errorf("%s: item with key '%s' should have been evicted.", name, key)
+				t.FailNow()
+			}
+		}
+
+		// growing past the whole cache drops the stale value
+		cache.Set("a", 2, WithSize(7))
+		if _, ok := cache.Get("a"); ok {
+			t.Errorf("%s: item with key 'a' should have been removed.", name)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that caches count the bytes of hits and misses.
+func Test_ByteHitRatio(t *testing.T) {
+	for name, cache := range newByteCaches(10) {
+		cache.Set("a", 0, WithSize(9))
+		cache.Get("a")
+		cache.Get("b", WithSize(3))
+
+		stats := cache.Stats()
+		if stats.HitBytes != 9 || stats.MissBytes != 3 {
+			t.Errorf("%s: expected 9 hit bytes and 3 missed bytes, got %d and %d",
+				name, stats.HitBytes, stats.MissBytes)
+			t.FailNow()
+		}
+
+		if stats.HitRatio() != 0.5 || stats.ByteHitRatio() != 0.75 {
+			t.Errorf("%s: expected a hit ratio of 0.5 and a byte hit ratio of 0.75, got %f and %f",
+				name, stats.HitRatio(), stats.ByteHitRatio())
+			t.FailNow()
+		}
+	}
+}
+
+/*********************************************************************/
+
+// Tests that every cache treats expired items as misses and removes them.
+func Test_TTL(t *testing.T) {
+	clock := NewManualClock(0)
+	caches := map[string]Cache[string, int]{
+		"FIFO":       mustCache(NewFIFOCache[string, int](3, WithClock(clock))),
+		"LRU":        mustCache(NewLRUCache[string, int](3, WithClock(clock))),
+		"LFU":        mustCache(NewLFUCache[string, int](3, WithClock(clock))),
+		"ARC":        mustCache(NewARCCache[string, int](3, WithClock(clock))),
+		"W-TINYLFU":  mustCache(NewWTinyLFUCache[string, int](3, WithClock(clock))),
+		"SIEVE":      mustCache(NewSIEVECache[string, int](3, WithClock(clock))),
+		"S3-FIFO":    mustCache(NewS3FIFOCache[string, int](3, WithClock(clock))),
+		"CLOCK":      mustCache(NewCLOCKCache[string, int](3, WithClock(clock))),
+		"CLOCK-PRO":  mustCache(NewCLOCKProCache[string, int](3, WithClock(clock))),
+		"HYPERBOLIC": mustCache(NewHyperbolicCache[string, int](3, 3, WithClock(clock))),
+	}
+
+	for name, cache := range caches {
+		clock.Set(0)
+		cache.Set("a", 0, WithTTL(5))
+		clock.Set(1)
+		cache.Set("b", 0)
+		clock.Set(2)
+		cache.Set("c", 0, WithTTL(5))
+
+		if _, ok := cache.Get("a"); !ok {
+			t.Errorf("%s: item with key 'a' should not have expired yet.", name)
+			t.FailNow()
+		}
+
+		// setting an existing item resets its TTL
+		clock.Set(4)
+		cache.Set("c", 0, WithTTL(5))
+
+		// the clock is now at time 5
+		clock.Set(5)
+		cache.Set("b", 0)
+
+		if _, ok := cache.Get("a"); ok {
+			t.Errorf("%s: item with key 'a' should have expired.", name)
+			t.FailNow()
+		}
+
+		if _, ok := cache.Get("b"); !ok {
+			t.Errorf("%s: item with key 'b' should never expire.", name)
+			t.FailNow()
+		}
+
+		if _, ok := cache.Get("c"); !ok {
+			t.Errorf("%s: item with key 'c' should not have expired yet.", name)
+			t.FailNow()
+		}
+
+		if cache.Delete("a") {
+			t.Errorf("%s: expired item with key 'a' should have been removed.", name)
+			t.FailNow()
+		}
+
+		stats := cache.Stats()
+		if stats.Hits != 3 || stats.Misses != 1 {
+			t.Errorf("%s: expected 3 hits and 1 miss, got %d hits and %d misses",
+				name, stats.Hits, stats.Misses)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a logical clock ticks once per request, so TTLs count requests.
+func Test_LogicalClock(t *testing.T) {
+	clock := NewLogicalClock()
+	fifo := mustCache(NewFIFOCache[string, int](2, WithClock(clock)))
+
+	// set at time 1, so the item expires at time 4
+	fifo.Set("a", 0, WithTTL(3))
+
+	for i := 0; i < 2; i++ {
+		if _, ok := fifo.Get("a"); !ok {
+			t.Errorf("Item with key 'a' should not have expired yet.")
+			t.FailNow()
+		}
+	}
+
+	if _, ok := fifo.Get("a"); ok {
+		t.Errorf("Item with key 'a' should have expired.")
+		t.FailNow()
+	}
+
+	if now := clock.Now(); now != 5 {
+		t.Errorf("Expected the clock to be at time 5, got %d", now)
+		t.FailNow()
+	}
+}
+
+// Tests that a manual clock only moves when it is set or advanced.
+func Test_ManualClock(t *testing.T) {
+	clock := NewManualClock(10)
+
+	if clock.Now() != 10 || clock.Now() != 10 {
+		t.Errorf("Expected the clock to stay at time 10, got %d", clock.Now())
+		t.FailNow()
+	}
+
+	clock.Advance(5)
+	if clock.Now() != 15 {
+		t.Errorf("Expected the clock to be at time 15, got %d", clock.Now())
+		t.FailNow()
+	}
+
+	clock.Set(3)
+	if clock.Now() != 3 {
+		t.Errorf("Expected the clock to be at time 3, got %d", clock.Now())
+		t.FailNow()
+	}
+}
+
+/*********************************************************************/
+
+// Tests that every cache tells which item it would evict next, without
+// changing what it holds.
+func Test_VictimCaches(t *testing.T) {
+	caches := map[string]VictimCache[string, int]{
+		"FIFO":      mustCache(NewFIFOCache[string, int](2)),
+		"LRU":       mustCache(NewLRUCache[string, int](2)),
+		"LFU":       mustCache(NewLFUCache[string, int](2)),
+		"ARC":       mustCache(NewARCCache[string, int](2)),
+		"W-TINYLFU": mustCache(NewWTinyLFUCache[string, int](2)),
+		"SIEVE":     mustCache(NewSIEVECache[string, int](2)),
+		"S3-FIFO":   mustCache(NewS3FIFOCache[string, int](2)),
+		"CLOCK":     mustCache(NewCLOCKCache[string, int](2)),
+		"CLOCK-PRO": mustCache(NewCLOCKProCache[string, int](2)),
+		"HYPERBOLIC": mustCache(NewHyperbolicCache[string, int](2, 2,
+			WithSource(rand.NewSource(1)))),
+	}
+
+	for name, cache := range caches {
+		cache.Set("a", 0)
+
+		if _, ok := cache.Victim(1); ok {
+			t.Errorf("%s: expected no victim while there is room", name)
+			t.FailNow()
+		}
+
+		cache.Set("b", 0)
+
+		if !cache.Contains("a") || !cache.Contains("b") || cache.Contains("c") {
+			t.Errorf("%s: expected to contain 'a' and 'b' but not 'c'", name)
+			t.FailNow()
+		}
+
+		victim, ok := cache.Victim(1)
+		if !ok || victim != "a" && victim != "b" {
+			t.Errorf("%s: expected 'a' or 'b' to be the victim, got %q", name, victim)
+			t.FailNow()
+		}
+
+		if stats := cache.Stats(); stats.Hits != 0 || stats.Evictions != 0 {
+			t.Errorf("%s: expected no hits or evictions, got %+v", name, stats)
+			t.FailNow()
+		}
+
+		// W-TinyLFU may evict the new item instead of the victim
+		if name == "W-TINYLFU" {
+			continue
+		}
+
+		cache.Set("c", 0)
+
+		if cache.Contains(victim) {
+			t.Errorf("%s: expected victim %q to be evicted", name, victim)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a S3-FIFO cache only looks at its oldest items to find its
+// victim, however many of them were used.
+func Test_S3FIFOVictimScan(t *testing.T) {
+	s3fifo := mustCache(NewS3FIFOCache[string, int](1000))
+
+	// fill the small queue with used items, and then a single unused one
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("%d", i)
+		s3fifo.Set(key, i)
+		s3fifo.Get(key)
+	}
+	s3fifo.Set("unused", 0)
+
+	// fill the rest of the cache, so that the next item needs room
+	for i := 100; s3fifo.small.used+s3fifo.main.used < 1000; i++ {
+		s3fifo.Set(fmt.Sprintf("%d", i), i)
+	}
+
+	// the unused item is too far back to be looked at, so the
+	// oldest of the used items is the victim
+	if victim, ok := s3fifo.Victim(1); !ok || victim != "0" {
+		t.Errorf("Expected victim 0, got %q", victim)
+		t.FailNow()
+	}
+}
+
+// Tests that a SIEVE cache only looks at the items next to its hand to
+// find its victim, however many of them were visited.
+func Test_SIEVEVictimScan(t *testing.T) {
+	sieve := mustCache(NewSIEVECache[string, int](1000))
+
+	// visit the 100 oldest items, and fill the rest of the cache
+	// with items that are not visited
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("%d", i)
+		sieve.Set(key, i)
+		sieve.Get(key)
+	}
+	for i := 100; i < 1000; i++ {
+		sieve.Set(fmt.Sprintf("%d", i), i)
+	}
+
+	// the unvisited items are too far from the hand to be looked
+	// at, so the item at the hand is the victim
+	if victim, ok := sieve.Victim(1); !ok || victim != "0" {
+		t.Errorf("Expected victim 0, got %q", victim)
+		t.FailNow()
+	}
+}
+
+// Tests that CLOCK and CLOCK-Pro caches only look at the items next to
+// their hands to find their victim, however many of them were referenced.
+func Test_CLOCKVictimScan(t *testing.T) {
+	clock := mustCache(NewCLOCKCache[string, int](1000))
+	pro := mustCache(NewCLOCKProCache[string, int](1000))
+
+	// reference the 100 oldest items, and fill the rest of the
+	// caches with items that are not referenced
+	for _, cache := range []Cache[string, int]{clock, pro} {
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("%d", i)
+			cache.Set(key, i)
+			cache.Get(key)
+		}
+		for i := 100; i < 1000; i++ {
+			cache.Set(fmt.Sprintf("%d", i), i)
+		}
+	}
+
+	// the unreferenced items are too far from the hands to be
+	// looked at, so the item at the hand is the victim
+	if victim, ok := clock.Victim(1); !ok || victim != "0" {
+		t.Errorf("Expected CLOCK victim 0, got %q", victim)
+		t.FailNow()
+	}
+
+	if victim, ok := pro.Victim(1); !ok || victim != "0" {
+		t.Errorf("Expected CLOCK-Pro victim 0, got %q", victim)
+		t.FailNow()
+	}
+}
+
+// Tests that a doorkeeper only admits keys it was asked to admit before,
+// and forgets them once it has remembered as many keys as it was sized for.
+func Test_Doorkeeper(t *testing.T) {
+	cache := NewAdmissionCache[string, int](mustCache(NewLRUCache[string, int](2)), NewDoorkeeper[string](2))
+
+	if set_success, err := cache.Set("a", 0); err != nil || set_success {
+		t.Errorf("Item with key 'a' should have been rejected the first time.")
+		t.FailNow()
+	}
+
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("Item with key 'a' should not be in the cache.")
+		t.FailNow()
+	}
+
+	if set_success, err := cache.Set("a", 1); err != nil || !set_success {
+		t.Errorf("Item with key 'a' should have been admitted the second time.")
+		t.FailNow()
+	}
+
+	if value, ok := cache.Get("a"); !ok || value != 1 {
+		t.Errorf("Item with key 'a' should be in the cache!")
+		t.FailNow()
+	}
+
+	// remembering 'x' and 'y' as well fills the doorkeeper, so it forgets them
+	cache.Set("x", 0)
+	cache.Set("y", 0)
+
+	if set_success, _ := cache.Set("x", 0); set_success {
+		t.Errorf("Item with key 'x' should have been forgotten and rejected.")
+		t.FailNow()
+	}
+
+	if cache.Rejections() != 4 {
+		t.Errorf("Expected 4 rejections, got %d", cache.Rejections())
+		t.FailNow()
+	}
+}
+
+// Tests that admission policies guarding a cache whose max capacity
+// counts bytes are sized by the number of items that fit in it, so that
+// a doorkeeper still forgets keys and a sketch still halves its counters.
+func Test_ByteAdmission(t *testing.T) {
+	doorkeeper := NewDoorkeeper[string](1<<30, WithByteCapacity())
+	cache := NewAdmissionCache[string, int](mustCache(NewFIFOCache[string, int](1<<30, WithByteCapacity())),
+		doorkeeper)
+
+	// 1024 items of 1 MiB fit in the cache
+	for i := 0; i < 2000; i++ {
+		cache.Set(fmt.Sprintf("%d", i), i, WithSize(1<<20))
+	}
+
+	if doorkeeper.keys != 1024 || doorkeeper.filter_bits != 16384 {
+		t.Errorf("Expected a doorkeeper sized for 1024 keys, got %d keys and %d bits",
+			doorkeeper.keys, doorkeeper.filter_bits)
+		t.FailNow()
+	}
+
+	if set_success, _ := cache.Set("0", 0, WithSize(1<<20)); set_success {
+		t.Errorf("Item with key '0' should have been forgotten and rejected.")
+		t.FailNow()
+	}
+
+	sketch := NewSketchAdmission[string](1<<30, WithByteCapacity())
+	cache = NewAdmissionCache[string, int](mustCache(NewFIFOCache[string, int](1<<30, WithByteCapacity())),
+		sketch)
+
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("%d", i), i, WithSize(1<<20))
+	}
+
+	if sketch.sketch.width != sketchWidth(1024) || sketch.sketch.sample_size != 10*1024 {
+		t.Errorf("Expected a sketch sized for 1024 keys, got a width of %d and a sample size of %d",
+			sketch.sketch.width, sketch.sketch.sample_size)
+		t.FailNow()
+	}
+
+	// a full sample of other requests halves the count of 'a'
+	for i := 0; i < max_sketch_count; i++ {
+		cache.Get("a")
+	}
+	for i := 0; i < 10*1024; i++ {
+		cache.Get(fmt.Sprintf("x%d", i))
+	}
+
+	if count := sketch.sketch.estimate(keyHash("a")); count > max_sketch_count/2 {
+		t.Errorf("Expected the count of 'a' to have been halved, got %d", count)
+		t.FailNow()
+	}
+}
+
+// Tests that a sketch admission filter in front of a hyperbolic cache
+// only admits a new item once it was requested more often than its victim.
+func Test_SketchAdmission(t *testing.T) {
+	hyperbolic := mustCache(NewHyperbolicCache[string, int](2, 2, WithSource(rand.NewSource(1))))
+	cache := NewAdmissionCache[string, int](hyperbolic, NewSketchAdmission[string](2))
+
+	// there is room for 'a' and 'b', which are then requested 3 more times each
+	cache.Set("a", 0)
+	cache.Set("b", 0)
+	for i := 0; i < 3; i++ {
+		cache.Get("a")
+		cache.Get("b")
+	}
+
+	// a Get that misses and the Set that fetches its item are one request
+	for i := 0; i < 4; i++ {
+		if _, ok := cache.Get("c"); ok {
+			t.Errorf("Item with key 'c' should not be in the cache.")
+			t.FailNow()
+		}
+
+		if set_success, _ := cache.Set("c", 0); set_success {
+			t.Errorf("Item with key 'c' should have been rejected after %d requests.", i+1)
+			t.FailNow()
+		}
+	}
+
+	// the fifth request outnumbers the victim's
+	cache.Get("c")
+	if set_success, err := cache.Set("c", 0); err != nil || !set_success {
+		t.Errorf("Item with key 'c' should have been admitted.")
+		t.FailNow()
+	}
+
+	if !hyperbolic.Contains("c") || cache.Rejections() != 4 || cache.Stats().Evictions != 1 {
+		t.Errorf("Expected 'c' in the cache after 4 rejections and 1 eviction, got %d and %d",
+			cache.Rejections(), cache.Stats().Evictions)
+		t.FailNow()
+	}
+
+	// keys that are only ever set are counted too, and win admission
+	// once they are set more often than the victim was requested
+	for i := 0; i < 4; i++ {
+		if set_success, _ := cache.Set("d", 0); set_success {
+			t.Errorf("Item with key 'd' should have been rejected after %d sets.", i+1)
+			t.FailNow()
+		}
+	}
+
+	if set_success, _ := cache.Set("d", 0); !set_success {
+		t.Errorf("Item with key 'd' should have been admitted.")
+		t.FailNow()
+	}
+}
+
+// Tests that asking a sampled cache for its victim leaves its retained
+// candidates and inflation alone until the victim is evicted.
+func Test_SampledVictimUnchanged(t *testing.T) {
+	sampled := mustCache(NewSampledCache[string, int](3, 3, GDSFPriority,
+		WithRetainedCandidates(1), WithSource(rand.NewSource(1))))
+
+	sampled.Set("a", 0)
+	sampled.Set("b", 0, WithCost(2))
+	sampled.Set("c", 0, WithCost(3))
+
+	victim, ok := sampled.Victim(1)
+	if !ok || victim != "a" || len(sampled.retained) != 0 || sampled.inflation != 0 {
+		t.Errorf("Expected victim 'a' without retained candidates or inflation, got %q, %d and %f",
+			victim, len(sampled.retained), sampled.inflation)
+		t.FailNow()
+	}
+
+	// evicting the victim makes the changes of its eviction
+	sampled.Set("d", 0)
+
+	if sampled.Contains("a") || len(sampled.retained) != 1 || sampled.retained[0].key != "b" ||
+		sampled.inflation != 1 {
+		t.Errorf("Expected 'a' evicted, 'b' retained and an inflation of 1, got %d retained and %f",
+			len(sampled.retained), sampled.inflation)
+		t.FailNow()
+	}
+}
+
+// Tests that the victim a sampled cache picks for a rejected item is not
+// evicted by a later Set of an item of a different size.
+func Test_SampledVictimRejected(t *testing.T) {
+	sampled := mustCache(NewSampledCache[string, int](3, 3, GDSFPriority,
+		WithByteCapacity(), WithSource(rand.NewSource(1))))
+	cache := NewAdmissionCache[string, int](sampled, NewSizeThreshold[string](1))
+
+	cache.Set("a", 0)
+	cache.Set("b", 0, WithCost(2))
+
+	// 'a' is picked as the victim of 'big', which is rejected
+	if set_success, _ := cache.Set("big", 0, WithSize(2)); set_success {
+		t.Errorf("Item with key 'big' should have been rejected.")
+		t.FailNow()
+	}
+
+	// 'c' fits, and has a lower priority than 'a'
+	cache.Set("c", 0, WithCost(0.5))
+	cache.Set("d", 0)
+
+	if sampled.Contains("c") || !sampled.Contains("a") {
+		t.Errorf("Expected 'c' to be evicted instead of 'a'.")
+		t.FailNow()
+	}
+}
+
+// Tests that a size threshold rejects large new items, but lets updates
+// of items already in the cache through.
+func Test_SizeThreshold(t *testing.T) {
+	cache := NewAdmissionCache[string, int](mustCache(NewFIFOCache[string, int](10, WithByteCapacity())),
+		NewSizeThreshold[string](4))
+
+	if set_success, err := cache.Set("a", 0, WithSize(4)); err != nil || !set_success {
+		t.Errorf("Item with key 'a' should have been admitted.")
+		t.FailNow()
+	}
+
+	if set_success, err := cache.Set("b", 0, WithSize(5)); err != nil || set_success {
+		t.Errorf("Item with key 'b' should have been rejected.")
+		t.FailNow()
+	}
+
+	if set_success, err := cache.Set("a", 1, WithSize(8)); err != nil || !set_success {
+		t.Errorf("Update of item with key 'a' should have been set.")
+		t.FailNow()
+	}
+
+	if _, err := cache.Set("c", 0, WithSize(-1)); !errors.Is(err, ErrInvalidItemOption) {
+		t.Errorf("Expected an invalid item option error, got: %v", err)
+		t.FailNow()
+	}
+
+	// a cache that can not tell what it holds drops stale values of rejected items
+	lru := mustCache(NewLRUCache[string, int](10, WithByteCapacity()))
+	hidden := NewAdmissionCache[string, int](struct{ Cache[string, int] }{lru}, NewSizeThreshold[string](4))
+
+	lru.Set("a", 0, WithSize(4))
+	if set_success, _ := hidden.Set("a", 1, WithSize(5)); set_success || lru.Contains("a") {
+		t.Errorf("Stale item with key 'a' should have been deleted.")
+		t.FailNow()
+	}
+}
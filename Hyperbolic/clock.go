@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"time"
+)
+
+// A Clock tells a cache the current time. Caches read it once per Get
+// or Set, and TTLs given with WithTTL are in the same units as it.
+type Clock interface {
+
+	// Now returns the current time.
+	Now() int
+}
+
+// A WallClock is a Clock that reads the system time,
+// in nanoseconds since the Unix epoch.
+type WallClock struct{}
+
+// Now returns the system time in nanoseconds since the Unix epoch.
+func (WallClock) Now() int {
+	return int(time.Now().UnixNano())
+}
+
+// A LogicalClock is a Clock that counts requests:
+// every call to Now advances it by one.
+type LogicalClock struct {
+
+	// number of times Now has been called
+	now int
+}
+
+// NewLogicalClock returns a LogicalClock that has not counted any requests.
+func NewLogicalClock() *LogicalClock {
+	return &LogicalClock{now: 0}
+}
+
+// Now advances the LogicalClock by one and returns the new count.
+func (clock *LogicalClock) Now() int {
+	clock.now += 1
+	return clock.now
+}
+
+// A ManualClock is a Clock that only moves when told to,
+// such as in tests or when replaying a trace's timestamps.
+type ManualClock struct {
+
+	// the current time
+	now int
+}
+
+// NewManualClock returns a ManualClock set to the given time.
+func NewManualClock(now int) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the time the ManualClock was last set to.
+func (clock *ManualClock) Now() int {
+	return clock.now
+}
+
+// Set sets the ManualClock to the given time.
+func (clock *ManualClock) Set(now int) {
+	clock.now = now
+}
+
+// Advance moves the ManualClock forward by the given amount of time.
+func (clock *ManualClock) Advance(elapsed int) {
+	clock.now += elapsed
+}
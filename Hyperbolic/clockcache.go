@@ -0,0 +1,335 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// largest number of items a CLOCKCache's hand looks at to find its
+// victim without evicting it, so that finding it does not take longer
+// with more items
+const clock_victim_scan = 16
+
+// A CLOCKCache is a fixed-size, in-memory cache with CLOCK eviction, an
+// approximation of LRU. Its items sit on a circle, and hits only set
+// the referenced bit of an item instead of moving it like an LRUCache
+// does. A hand walks the circle, clearing the referenced bits of the
+// items it passes and evicting the first item that was not referenced.
+// New items are set just behind the hand, so that the hand reaches
+// them last.
+type CLOCKCache[K comparable, V any] struct {
+
+	// total number of items (or bytes, in byte capacity
+	// mode) the CLOCKCache can store
+	max_capacity int
+
+	// whether max_capacity counts bytes instead of items
+	by_bytes bool
+
+	// total number of items currently in the CLOCKCache
+	size int
+
+	// how much of max_capacity the items currently in the CLOCKCache use
+	used int
+
+	// mapping of keys to items in the CLOCKCache
+	keys_to_items map[K]*list.Element
+
+	// circle of items in the CLOCKCache, going on from the
+	// back of the linked list to the front
+	linked_list *list.List
+
+	// next item the hand looks at, or nil if the CLOCKCache is empty
+	hand *list.Element
+
+	// number of hits from the CLOCKCache
+	hits int
+
+	// number of misses from the CLOCKCache
+	misses int
+
+	// total size of the items hit in the CLOCKCache
+	hit_bytes int
+
+	// total size of the items missed in the CLOCKCache
+	miss_bytes int
+
+	// number of items evicted from the CLOCKCache
+	evictions int
+
+	// clock that tells the CLOCKCache when items expire
+	clock Clock
+}
+
+// A CLOCKCacheItem holds a key, value pair to be put in a linked list.
+type CLOCKCacheItem[K comparable, V any] struct {
+	key        K
+	value      V
+	size       int
+	expires_at int
+
+	// whether the item was used since the hand last passed it
+	referenced bool
+}
+
+// NewCLOCKCache returns a pointer to a new, empty CLOCKCache.
+func NewCLOCKCache[K comparable, V any](max_capacity int, opts ...Option) (*CLOCKCache[K, V], error) {
+
+	if err := validateCapacity(max_capacity); err != nil {
+		return nil, err
+	}
+
+	config := newOptions(opts)
+
+	// create and initialize a new CLOCKCache
+	return &CLOCKCache[K, V]{
+		max_capacity:  max_capacity,
+		by_bytes:      config.by_bytes,
+		clock:         config.clock,
+		size:          0,
+		used:          0,
+		keys_to_items: make(map[K]*list.Element),
+		linked_list:   list.New(),
+		hand:          nil,
+		hits:          0,
+		misses:        0,
+	}, nil
+}
+
+// Get returns the value of the item with the key and a success boolean
+// indicating if the item was found in the cache.
+// This operation sets the referenced bit of the item.
+func (cc *CLOCKCache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	// read the time of this request
+	now := cc.clock.Now()
+
+	// check if there is an item with the given key
+	existing_item, ok := cc.keys_to_items[key]
+
+	// an expired item is removed and counts as a miss
+	if ok && expired(existing_item.Value.(*CLOCKCacheItem[K, V]).expires_at, now) {
+		cc.remove(existing_item)
+		ok = false
+	}
+
+	if !ok {
+		cc.misses++
+		cc.miss_bytes += requestSize(opts)
+		return value, false
+	}
+
+	cc.hits++
+
+	item := existing_item.Value.(*CLOCKCacheItem[K, V])
+	item.referenced = true
+	cc.hit_bytes += item.size
+
+	return item.value, true
+}
+
+// Set sets the value of the item with the given key,
+// possibly evicting items to make room for a new key insertion.
+// This operation sets the referenced bit of an item already in the cache.
+// Returns true if the item was added/updated successfully, else false.
+func (cc *CLOCKCache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	// read the time of this request
+	operation_timestamp := cc.clock.Now()
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
+
+	// can not set if cache max capacity is 0!
+	if cc.max_capacity == 0 {
+		return false, nil
+	}
+
+	item_weight := weight(cc.by_bytes, description.size)
+
+	// check if there is an existing item with the key
+	existing_item, ok := cc.keys_to_items[key]
+
+	// an item larger than the whole cache can never fit,
+	// and any older value for its key is now stale
+	if item_weight > cc.max_capacity {
+		if ok {
+			cc.remove(existing_item)
+		}
+		return false, nil
+	}
+
+	if ok {
+		item := existing_item.Value.(*CLOCKCacheItem[K, V])
+		item.value = value
+		item.expires_at = expiry(operation_timestamp, description.ttl)
+		item.referenced = true
+
+		// account for a change in size, evicting other
+		// items if the item no longer fits
+		cc.used += item_weight - weight(cc.by_bytes, item.size)
+		item.size = description.size
+		cc.makeRoom(0, existing_item)
+
+		return true, nil
+	}
+
+	// item with the key does not exist, so check if we need to evict
+	cc.makeRoom(item_weight, nil)
+
+	// insert the item just behind the hand
+	item := &CLOCKCacheItem[K, V]{key: key, value: value, size: description.size,
+		expires_at: expiry(operation_timestamp, description.ttl)}
+	if cc.hand == nil {
+		cc.hand = cc.linked_list.PushBack(item)
+		cc.keys_to_items[key] = cc.hand
+	} else {
+		cc.keys_to_items[key] = cc.linked_list.InsertBefore(item, cc.hand)
+	}
+
+	// update the size of the CLOCKCache
+	cc.size++
+	cc.used += item_weight
+
+	return true, nil
+}
+
+// makeRoom moves the hand and evicts items until an item of the given
+// weight fits in the CLOCKCache. The protected item is never evicted.
+func (cc *CLOCKCache[K, V]) makeRoom(item_weight int, protected *list.Element) {
+
+	for cc.used+item_weight > cc.max_capacity {
+
+		victim := cc.sweep(protected, true)
+		if victim == nil {
+			return
+		}
+
+		// removing the victim moves the hand on to the next item
+		cc.remove(victim)
+		cc.evictions++
+	}
+}
+
+// sweep walks the circle from the hand and returns the first item other
+// than the protected item that was not referenced. If clear_referenced
+// is true, the hand moves and clears the referenced bits of the items it
+// passes; otherwise the walk only looks, at no more than
+// clock_victim_scan items. Returns nil if there is no item but the
+// protected one.
+func (cc *CLOCKCache[K, V]) sweep(protected *list.Element, clear_referenced bool) *list.Element {
+
+	element := cc.hand
+
+	// a walk that only looks evicts the first item it passes if
+	// every item it looks at was referenced, since a walk that
+	// clears would come back around to it
+	var first *list.Element
+
+	// every item is passed at most twice, once to clear it
+	// and once to evict it
+	limit := 2 * cc.linked_list.Len()
+	if !clear_referenced {
+		limit = min(limit, clock_victim_scan)
+	}
+
+	for steps := 0; element != nil && steps <= limit; steps++ {
+
+		if element != protected {
+			item := element.Value.(*CLOCKCacheItem[K, V])
+			if !item.referenced {
+				return element
+			}
+
+			if clear_referenced {
+				item.referenced = false
+			} else if first == nil {
+				first = element
+			}
+		}
+
+		element = clockwise(cc.linked_list, element)
+		if clear_referenced {
+			cc.hand = element
+		}
+	}
+
+	return first
+}
+
+// clockwise returns the item after the given item on the circle formed
+// by a linked list, which is the front item after the back one.
+func clockwise(linked_list *list.List, element *list.Element) *list.Element {
+	if next := element.Next(); next != nil {
+		return next
+	}
+	return linked_list.Front()
+}
+
+// Delete removes the item with the given key from the CLOCKCache.
+// Returns true if the item was found and removed, else false.
+func (cc *CLOCKCache[K, V]) Delete(key K) (success bool) {
+
+	// check if there is an item with the given key
+	existing_item, ok := cc.keys_to_items[key]
+	if !ok {
+		return false
+	}
+
+	cc.remove(existing_item)
+
+	return true
+}
+
+// remove unlinks an item from the linked list and the map, moving the
+// hand on to the next item if it points at the item.
+func (cc *CLOCKCache[K, V]) remove(element *list.Element) {
+
+	if cc.hand == element {
+		cc.hand = clockwise(cc.linked_list, element)
+		if cc.hand == element {
+			cc.hand = nil
+		}
+	}
+
+	item := cc.linked_list.Remove(element).(*CLOCKCacheItem[K, V])
+	delete(cc.keys_to_items, item.key)
+
+	// update the size of the CLOCKCache
+	cc.size--
+	cc.used -= weight(cc.by_bytes, item.size)
+}
+
+// Contains returns true if an item with the key is in the CLOCKCache,
+// whether or not it has expired, without counting as a use.
+func (cc *CLOCKCache[K, V]) Contains(key K) bool {
+	_, ok := cc.keys_to_items[key]
+	return ok
+}
+
+// Victim returns the key of the item the CLOCKCache would evict first to
+// make room for a new item of the given size, and true, or false if
+// the item fits without evicting anything. Only the items next to the
+// hand are looked at, so the victim is an estimate when all of them
+// were referenced.
+func (cc *CLOCKCache[K, V]) Victim(size int) (key K, ok bool) {
+
+	if cc.used+weight(cc.by_bytes, size) <= cc.max_capacity {
+		return key, false
+	}
+
+	victim := cc.sweep(nil, false)
+	if victim == nil {
+		return key, false
+	}
+
+	return victim.Value.(*CLOCKCacheItem[K, V]).key, true
+}
+
+// Stats returns statistics about how many search hits and misses have
+// occurred in the CLOCKCache.
+func (cc *CLOCKCache[K, V]) Stats() *Stats {
+	return &Stats{Hits: cc.hits, Misses: cc.misses,
+		HitBytes: cc.hit_bytes, MissBytes: cc.miss_bytes, Evictions: cc.evictions}
+}
@@ -0,0 +1,509 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// largest number of items a CLOCKProCache looks at from each of its cold
+// and hot hands to find its victim, so that finding it does not take
+// longer with more items
+const clockpro_victim_scan = 16
+
+// status of an item in a CLOCKProCache
+type clockProStatus int
+
+const (
+	// a resident item that was used again while it was cold
+	clockpro_hot clockProStatus = iota
+
+	// a resident item that is new or was demoted from hot
+	clockpro_cold
+
+	// a key recently evicted while cold, without its value,
+	// which becomes hot if it is set again
+	clockpro_test
+)
+
+// A CLOCKProCache is a fixed-size, in-memory cache with CLOCK-Pro
+// eviction, an approximation of LIRS. Its items sit on a circle, are
+// hot or cold, and hits only set their referenced bit. New items are
+// cold, and a cold item that was referenced when the cold hand reaches
+// it becomes hot instead of being evicted. Evicted cold items stay on
+// the circle as test keys for a while, and a test key that is set again
+// is hot at once. A hot hand demotes hot items that were not referenced
+// since it last passed them, keeping the hot items within their share
+// of max_capacity, and a test hand forgets the oldest test keys. The
+// share of the cold items grows when test keys are set again and
+// shrinks when they are forgotten instead.
+type CLOCKProCache[K comparable, V any] struct {
+
+	// total number of items (or bytes, in byte capacity
+	// mode) the CLOCKProCache can store
+	max_capacity int
+
+	// whether max_capacity counts bytes instead of items
+	by_bytes bool
+
+	// how much of max_capacity the cold items are meant to use,
+	// leaving the rest for the hot items
+	cold_capacity int
+
+	// how much of max_capacity the hot items use
+	hot_used int
+
+	// how much of max_capacity the cold items use
+	cold_used int
+
+	// how much the items the test keys were evicted from used,
+	// which is at most max_capacity
+	test_used int
+
+	// mapping of keys to items and test keys in the CLOCKProCache
+	keys_to_items map[K]*list.Element
+
+	// circle of items and test keys, going on from the
+	// back of the linked list to the front
+	linked_list *list.List
+
+	// next item the hot hand looks at, just ahead of where new
+	// items are set, or nil if the circle is empty
+	hand_hot *list.Element
+
+	// next item the cold hand looks at, or nil if the circle is empty
+	hand_cold *list.Element
+
+	// next item the test hand looks at, or nil if the circle is empty
+	hand_test *list.Element
+
+	// number of hits from the CLOCKProCache
+	hits int
+
+	// number of misses from the CLOCKProCache
+	misses int
+
+	// total size of the items hit in the CLOCKProCache
+	hit_bytes int
+
+	// total size of the items missed in the CLOCKProCache
+	miss_bytes int
+
+	// number of items evicted from the CLOCKProCache
+	evictions int
+
+	// clock that tells the CLOCKProCache when items expire
+	clock Clock
+}
+
+// A CLOCKProCacheItem holds a key, value pair to be put in a linked list.
+type CLOCKProCacheItem[K comparable, V any] struct {
+	key        K
+	value      V
+	size       int
+	expires_at int
+
+	// whether the item is hot, cold or a test key
+	status clockProStatus
+
+	// whether the item was used since a hand last passed it
+	referenced bool
+}
+
+// NewCLOCKProCache returns a pointer to a new, empty CLOCKProCache.
+func NewCLOCKProCache[K comparable, V any](max_capacity int, opts ...Option) (*CLOCKProCache[K, V], error) {
+
+	if err := validateCapacity(max_capacity); err != nil {
+		return nil, err
+	}
+
+	config := newOptions(opts)
+
+	// create and initialize a new CLOCKProCache, whose items are
+	// all allowed to be cold until test keys are forgotten
+	return &CLOCKProCache[K, V]{
+		max_capacity:  max_capacity,
+		by_bytes:      config.by_bytes,
+		clock:         config.clock,
+		cold_capacity: max(max_capacity, 1),
+		keys_to_items: make(map[K]*list.Element),
+		linked_list:   list.New(),
+		hits:          0,
+		misses:        0,
+	}, nil
+}
+
+// Get returns the value of the item with the key and a success boolean
+// indicating if the item was found in the cache. A test key is a miss.
+// This operation sets the referenced bit of the item.
+func (pro *CLOCKProCache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	// read the time of this request
+	now := pro.clock.Now()
+
+	// check if there is an item in the cache with the given key
+	existing_item, ok := pro.keys_to_items[key]
+	if ok && !pro.resident(existing_item) {
+		ok = false
+	}
+
+	// an expired item is removed and counts as a miss
+	if ok && expired(existing_item.Value.(*CLOCKProCacheItem[K, V]).expires_at, now) {
+		pro.remove(existing_item)
+		ok = false
+	}
+
+	if !ok {
+		pro.misses++
+		pro.miss_bytes += requestSize(opts)
+		return value, false
+	}
+
+	pro.hits++
+
+	item := existing_item.Value.(*CLOCKProCacheItem[K, V])
+	item.referenced = true
+	pro.hit_bytes += item.size
+
+	return item.value, true
+}
+
+// Set sets the value of the item with the given key,
+// possibly evicting items to make room for a new key insertion.
+// New items are cold, unless their key is a test key, in which case
+// they are hot and the cold items' share of max_capacity grows.
+// This operation sets the referenced bit of an item already in the cache.
+// Returns true if the item was added/updated successfully, else false.
+func (pro *CLOCKProCache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	// read the time of this request
+	operation_timestamp := pro.clock.Now()
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
+
+	// can not set if cache max capacity is 0!
+	if pro.max_capacity == 0 {
+		return false, nil
+	}
+
+	item_weight := weight(pro.by_bytes, description.size)
+
+	// check if there is an existing item with the key
+	existing_item, ok := pro.keys_to_items[key]
+
+	// an item larger than the whole cache can never fit,
+	// and any older value for its key is now stale
+	if item_weight > pro.max_capacity {
+		if ok && pro.resident(existing_item) {
+			pro.remove(existing_item)
+		}
+		return false, nil
+	}
+
+	if ok && pro.resident(existing_item) {
+		item := existing_item.Value.(*CLOCKProCacheItem[K, V])
+		item.value = value
+		item.expires_at = expiry(operation_timestamp, description.ttl)
+		item.referenced = true
+
+		// account for a change in size, evicting other
+		// items if the item no longer fits
+		*pro.used(item.status) += item_weight - weight(pro.by_bytes, item.size)
+		item.size = description.size
+		pro.makeRoom(0, existing_item)
+
+		return true, nil
+	}
+
+	// a test key was evicted too early, so its item is hot
+	// and the cold items get a larger share
+	status := clockpro_cold
+	if ok {
+		test_weight := weight(pro.by_bytes, existing_item.Value.(*CLOCKProCacheItem[K, V]).size)
+		pro.cold_capacity = min(pro.cold_capacity+test_weight, pro.max_capacity)
+		pro.remove(existing_item)
+		status = clockpro_hot
+	}
+
+	// check if we need to evict
+	pro.makeRoom(item_weight, nil)
+
+	// insert the item just behind the hot hand
+	item := &CLOCKProCacheItem[K, V]{key: key, value: value, size: description.size,
+		expires_at: expiry(operation_timestamp, description.ttl), status: status}
+
+	var element *list.Element
+	if pro.hand_hot == nil {
+		element = pro.linked_list.PushBack(item)
+		pro.hand_hot, pro.hand_cold, pro.hand_test = element, element, element
+	} else {
+		element = pro.linked_list.InsertBefore(item, pro.hand_hot)
+	}
+
+	pro.keys_to_items[key] = element
+	*pro.used(status) += item_weight
+
+	pro.balance(element)
+
+	return true, nil
+}
+
+// makeRoom evicts cold items until an item of the given weight fits in
+// the CLOCKProCache, demoting hot items if there are no cold items left
+// to evict. The protected item is never evicted or demoted.
+func (pro *CLOCKProCache[K, V]) makeRoom(item_weight int, protected *list.Element) {
+
+	for pro.hot_used+pro.cold_used+item_weight > pro.max_capacity {
+		if !pro.evictCold(protected) && !pro.demoteHot(protected) {
+			return
+		}
+	}
+}
+
+// evictCold moves the cold hand until it reaches a cold item that was
+// not referenced, which it evicts, leaving its key as a test key.
+// Referenced cold items it passes become hot. Returns false if no item
+// was evicted.
+func (pro *CLOCKProCache[K, V]) evictCold(protected *list.Element) bool {
+
+	// every cold item is passed at most twice,
+	// once to promote it and once to evict it
+	limit := 2 * pro.linked_list.Len()
+
+	for steps := 0; pro.hand_cold != nil && steps < limit; steps++ {
+
+		element := pro.hand_cold
+		pro.hand_cold = clockwise(pro.linked_list, element)
+
+		item := element.Value.(*CLOCKProCacheItem[K, V])
+		if item.status != clockpro_cold || element == protected {
+			continue
+		}
+
+		if item.referenced {
+			pro.change(item, clockpro_hot)
+			pro.balance(protected)
+			continue
+		}
+
+		var zero V
+		item.value = zero
+		pro.change(item, clockpro_test)
+		pro.evictions++
+
+		pro.forget()
+
+		return true
+	}
+
+	return false
+}
+
+// demoteHot moves the hot hand until it reaches a hot item that was not
+// referenced since the hand last passed it, which it demotes to cold.
+// Test keys the hot hand passes are forgotten. Returns false if no item
+// was demoted.
+func (pro *CLOCKProCache[K, V]) demoteHot(protected *list.Element) bool {
+
+	// every hot item is passed at most twice,
+	// once to clear it and once to demote it
+	limit := 2 * pro.linked_list.Len()
+
+	for steps := 0; pro.hand_hot != nil && steps < limit; steps++ {
+
+		element := pro.hand_hot
+		item := element.Value.(*CLOCKProCacheItem[K, V])
+
+		// removing the test key moves the hand on to the next item
+		if item.status == clockpro_test {
+			pro.expire(element)
+			continue
+		}
+
+		pro.hand_hot = clockwise(pro.linked_list, element)
+
+		if item.status != clockpro_hot || element == protected {
+			continue
+		}
+
+		if item.referenced {
+			item.referenced = false
+			continue
+		}
+
+		pro.change(item, clockpro_cold)
+
+		return true
+	}
+
+	return false
+}
+
+// balance demotes hot items until they are within their share of
+// max_capacity. The protected item is never demoted.
+func (pro *CLOCKProCache[K, V]) balance(protected *list.Element) {
+	for pro.hot_used > pro.max_capacity-pro.cold_capacity {
+		if !pro.demoteHot(protected) {
+			return
+		}
+	}
+}
+
+// forget moves the test hand, forgetting test keys until they were
+// evicted from items that use at most max_capacity.
+func (pro *CLOCKProCache[K, V]) forget() {
+
+	for pro.test_used > pro.max_capacity && pro.hand_test != nil {
+
+		element := pro.hand_test
+		item := element.Value.(*CLOCKProCacheItem[K, V])
+
+		if item.status != clockpro_test {
+			pro.hand_test = clockwise(pro.linked_list, element)
+			continue
+		}
+
+		// removing the test key moves the hand on to the next item
+		pro.expire(element)
+	}
+}
+
+// expire removes a test key whose key was not set again while it was
+// remembered, shrinking the cold items' share of max_capacity.
+func (pro *CLOCKProCache[K, V]) expire(element *list.Element) {
+	item_weight := weight(pro.by_bytes, element.Value.(*CLOCKProCacheItem[K, V]).size)
+	pro.remove(element)
+	pro.cold_capacity = max(pro.cold_capacity-item_weight, 1)
+}
+
+// change changes the status of an item, clearing its referenced bit.
+func (pro *CLOCKProCache[K, V]) change(item *CLOCKProCacheItem[K, V], status clockProStatus) {
+
+	item_weight := weight(pro.by_bytes, item.size)
+
+	*pro.used(item.status) -= item_weight
+	item.status = status
+	item.referenced = false
+	*pro.used(status) += item_weight
+}
+
+// used returns how much of max_capacity the items of a status use.
+func (pro *CLOCKProCache[K, V]) used(status clockProStatus) *int {
+	switch status {
+	case clockpro_hot:
+		return &pro.hot_used
+	case clockpro_cold:
+		return &pro.cold_used
+	default:
+		return &pro.test_used
+	}
+}
+
+// resident returns true if an item is hot or cold, rather than a test key.
+func (pro *CLOCKProCache[K, V]) resident(element *list.Element) bool {
+	return element.Value.(*CLOCKProCacheItem[K, V]).status != clockpro_test
+}
+
+// Delete removes the item with the given key from the CLOCKProCache.
+// Returns true if the item was found and removed, else false.
+func (pro *CLOCKProCache[K, V]) Delete(key K) (success bool) {
+
+	// check if there is an item in the cache with the given key
+	existing_item, ok := pro.keys_to_items[key]
+	if !ok || !pro.resident(existing_item) {
+		return false
+	}
+
+	pro.remove(existing_item)
+
+	return true
+}
+
+// remove unlinks an item from the linked list and the map, moving the
+// hands on to the next item if they point at the item.
+func (pro *CLOCKProCache[K, V]) remove(element *list.Element) {
+
+	next := clockwise(pro.linked_list, element)
+	if next == element {
+		next = nil
+	}
+
+	for _, hand := range []**list.Element{&pro.hand_hot, &pro.hand_cold, &pro.hand_test} {
+		if *hand == element {
+			*hand = next
+		}
+	}
+
+	item := pro.linked_list.Remove(element).(*CLOCKProCacheItem[K, V])
+	*pro.used(item.status) -= weight(pro.by_bytes, item.size)
+	delete(pro.keys_to_items, item.key)
+}
+
+// Contains returns true if an item with the key is hot or cold in the
+// CLOCKProCache, whether or not it has expired, without counting as a use.
+func (pro *CLOCKProCache[K, V]) Contains(key K) bool {
+	existing_item, ok := pro.keys_to_items[key]
+	return ok && pro.resident(existing_item)
+}
+
+// Victim returns the key of the item the CLOCKProCache would evict first
+// to make room for a new item of the given size, and true, or false if
+// the item fits without evicting anything.
+// The victim is the first cold item from the cold hand that was not
+// referenced, or else the first hot item from the hot hand that would
+// be demoted, since referenced cold items become hot instead. Only the
+// items next to the hands are looked at, so the victim is an estimate,
+// and the circle is only walked further if none of them are resident.
+func (pro *CLOCKProCache[K, V]) Victim(size int) (key K, ok bool) {
+
+	if pro.hot_used+pro.cold_used+weight(pro.by_bytes, size) <= pro.max_capacity {
+		return key, false
+	}
+
+	for _, limit := range []int{clockpro_victim_scan, pro.linked_list.Len()} {
+
+		cold_unreferenced, cold := pro.find(pro.hand_cold, clockpro_cold, limit)
+		hot_unreferenced, hot := pro.find(pro.hand_hot, clockpro_hot, limit)
+
+		for _, victim := range []*list.Element{cold_unreferenced, hot_unreferenced, hot, cold} {
+			if victim != nil {
+				return victim.Value.(*CLOCKProCacheItem[K, V]).key, true
+			}
+		}
+	}
+
+	return key, false
+}
+
+// find walks the circle from the given hand, looking at no more than
+// limit items, and returns the first item of the status that was not
+// referenced and the first item of the status. Either is nil if there
+// is no such item.
+func (pro *CLOCKProCache[K, V]) find(hand *list.Element, status clockProStatus, limit int) (unreferenced *list.Element, first *list.Element) {
+
+	element := hand
+	for steps := 0; element != nil && steps < min(limit, pro.linked_list.Len()); steps++ {
+
+		item := element.Value.(*CLOCKProCacheItem[K, V])
+		if item.status == status {
+			if first == nil {
+				first = element
+			}
+			if !item.referenced {
+				return element, first
+			}
+		}
+
+		element = clockwise(pro.linked_list, element)
+	}
+
+	return unreferenced, first
+}
+
+// Stats returns statistics about how many search hits and misses have
+// occurred in the CLOCKProCache.
+func (pro *CLOCKProCache[K, V]) Stats() *Stats {
+	return &Stats{Hits: pro.hits, Misses: pro.misses,
+		HitBytes: pro.hit_bytes, MissBytes: pro.miss_bytes, Evictions: pro.evictions}
+}
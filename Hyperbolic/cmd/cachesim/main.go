@@ -0,0 +1,278 @@
+// Command cachesim replays cache traces and synthetic workloads against
+// caching policies and prints the hit ratio and byte hit ratio of every
+// policy at every max capacity, or with -output csv or jsonl, one row of
+// statistics per experiment. Traces can be in any format package trace
+// reads, and workloads are written as package workload's Specs. With
+// -plots, hit ratio and miss ratio curves of every source are written to
+// a directory as SVG files too. With -byte-capacity, max capacities count
+// the bytes of the requested items instead of the items.
+//
+// Usage:
+//
+//	cachesim -traces traces/cluster052 -policies FIFO,LRU,HYPERBOLIC \
+//		-capacities 100,1000,10000 -sample-sizes 64
+//	cachesim -workloads 'zipf:keys=100000,skew=0.8;loop:keys=5000' -requests 1000000
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	cache "github.com/jimmytienhoangy/COS316_Project"
+	"github.com/jimmytienhoangy/COS316_Project/sim"
+	"github.com/jimmytienhoangy/COS316_Project/trace"
+	"github.com/jimmytienhoangy/COS316_Project/workload"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, "cachesim:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run parses the command line arguments and runs every experiment,
+// writing results to stdout in the order of the grid of experiments and
+// failed experiments to stderr. It returns an error if any experiment
+// failed.
+func run(args []string, stdout io.Writer, stderr io.Writer) error {
+
+	flags := flag.NewFlagSet("cachesim", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	traces := flags.String("traces", "", "comma-separated trace files to replay")
+	format := flags.String("format", "twitter",
+		"format of the trace files: twitter, arc, msr or oracle-general")
+	skip_malformed := flags.Bool("skip-malformed", false,
+		"skip malformed trace records instead of stopping")
+	policies := flags.String("policies", strings.Join(sim.Policies, ","),
+		"comma-separated caching policies to run")
+	workloads := flags.String("workloads", "", "semicolon-separated synthetic workloads to generate")
+	requests := flags.Int("requests", 1000000, "number of requests in each synthetic workload")
+	workload_seed := flags.Int64("workload-seed", 1, "seed of the synthetic workloads")
+	capacities := flags.String("capacities", "100,1000,10000",
+		"comma-separated max capacities to run each policy with")
+	byte_capacity := flags.Bool("byte-capacity", false,
+		"count max capacities in bytes, using the sizes of the requested items, instead of items")
+
+	// constant given by the academic paper on hyperbolic caching
+	sample_sizes := flags.String("sample-sizes", "64", "comma-separated sample sizes of sampling policies")
+
+	seeds := flags.String("seeds", "1", "comma-separated seeds of the caches' randomness")
+	workers := flags.Int("workers", 0, "number of experiments to run at once (default one per CPU)")
+	output := flags.String("output", "text", "format of the results: text, csv or jsonl")
+	plots := flags.String("plots", "", "directory to write hit ratio and miss ratio curves to as SVG files")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *traces == "" && *workloads == "" {
+		return fmt.Errorf("no trace files or workloads given with -traces or -workloads")
+	}
+
+	if *output != "text" && *output != "csv" && *output != "jsonl" {
+		return fmt.Errorf("-output: unknown format %q", *output)
+	}
+
+	var err error
+
+	grid := sim.Grid{Policies: strings.Split(*policies, ",")}
+
+	grid.Capacities, err = parseInts(*capacities)
+	if err != nil {
+		return fmt.Errorf("-capacities: %w", err)
+	}
+
+	grid.SampleSizes, err = parseInts(*sample_sizes)
+	if err != nil {
+		return fmt.Errorf("-sample-sizes: %w", err)
+	}
+
+	seed_ints, err := parseInts(*seeds)
+	if err != nil {
+		return fmt.Errorf("-seeds: %w", err)
+	}
+	for _, seed := range seed_ints {
+		grid.Seeds = append(grid.Seeds, int64(seed))
+	}
+
+	trace_format, err := trace.ParseFormat(*format)
+	if err != nil {
+		return fmt.Errorf("-format: %w", err)
+	}
+
+	// collect the trace files and workloads to run on
+	if *traces != "" {
+		for _, path := range strings.Split(*traces, ",") {
+			grid.Sources = append(grid.Sources,
+				sim.Trace{Path: path, Format: trace_format, SkipMalformed: *skip_malformed})
+		}
+	}
+
+	if *workloads != "" {
+		for _, text := range strings.Split(*workloads, ";") {
+			spec, err := workload.Parse(text)
+			if err != nil {
+				return fmt.Errorf("-workloads: %w", err)
+			}
+			grid.Sources = append(grid.Sources,
+				sim.Workload{Spec: spec, Requests: *requests, ValueSize: 1, Seed: *workload_seed})
+		}
+	}
+
+	// run each caching policy with every combination of
+	// sources, max capacities, sample sizes and seeds
+	var opts []cache.Option
+	if *byte_capacity {
+		opts = append(opts, cache.WithByteCapacity())
+	}
+
+	results := sim.RunAll(grid.Experiments(), *workers, opts...)
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintln(stderr, "cachesim:", result.Policy, "on", result.Source, "failed:", result.Err)
+		}
+	}
+
+	switch *output {
+	case "csv":
+		err = sim.WriteCSV(stdout, results)
+	case "jsonl":
+		err = sim.WriteJSONLines(stdout, results)
+	default:
+		writeText(stdout, grid, results)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if *plots != "" {
+		if err := writePlots(*plots, grid, results); err != nil {
+			return fmt.Errorf("-plots: %w", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d experiments failed", failed, len(results))
+	}
+
+	return nil
+}
+
+// writeText writes the results of the grid's experiments in sections
+// for every max capacity and source, one line per experiment that did
+// not fail.
+func writeText(stdout io.Writer, grid sim.Grid, results []sim.Result) {
+
+	// only tell results of sampling policies apart by the
+	// sample size asked for and seed if there is a choice of them
+	detailed := len(grid.SampleSizes) > 1 || len(grid.Seeds) > 1
+
+	for i, result := range results {
+
+		// start a new section for every max capacity and source
+		if i == 0 || result.Capacity != results[i-1].Capacity || result.Source.String() != results[i-1].Source.String() {
+			if i > 0 {
+				fmt.Fprintln(stdout)
+			}
+			fmt.Fprintln(stdout, "Testing max capacity [", result.Capacity, "] on "+
+				"["+result.Source.String()+"] ---")
+		}
+
+		if result.Err != nil {
+			continue
+		}
+
+		name := result.Policy
+		if detailed && sim.Sampled(result.Policy) {
+			sample_size := strconv.Itoa(result.RequestedSampleSize)
+			if result.SampleSize != result.RequestedSampleSize {
+				sample_size += fmt.Sprintf(" capped at %d", result.SampleSize)
+			}
+			name = fmt.Sprintf("%s [sample size %s, seed %d]", result.Policy, sample_size, result.Seed)
+		}
+
+		fmt.Fprintln(stdout, name, "Hit Ratio:", result.Stats.HitRatio(),
+			"Byte Hit Ratio:", result.Stats.ByteHitRatio())
+	}
+}
+
+// writePlots writes the hit ratio and miss ratio curves of every source
+// of the grid to the directory, creating it if it does not exist.
+func writePlots(directory string, grid sim.Grid, results []sim.Result) error {
+
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+
+	plots := []struct {
+		name  string
+		label string
+		ratio func(*cache.Stats) float64
+	}{
+		{"hit-ratio", "Hit ratio", sim.HitRatio},
+		{"miss-ratio", "Miss ratio", sim.MissRatio},
+	}
+
+	for _, source := range grid.Sources {
+		for _, plot := range plots {
+			curves := sim.Curves(results, source.String(), plot.ratio)
+
+			path := filepath.Join(directory, plotName(source.String())+"-"+plot.name+".svg")
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+
+			err = sim.WriteSVG(file, plot.label+" on "+source.String(), plot.label, curves)
+			if close_err := file.Close(); err == nil {
+				err = close_err
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// plotName turns the name of a source into part of a file name, replacing
+// every character other than letters, digits, '-', '_' and '.' with '_'.
+func plotName(source string) string {
+	return strings.Map(func(char rune) rune {
+		if char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' ||
+			char == '-' || char == '_' || char == '.' {
+			return char
+		}
+		return '_'
+	}, source)
+}
+
+// parseInts parses a comma-separated list of integers.
+func parseInts(list string) ([]int, error) {
+
+	values := []int{}
+
+	for _, field := range strings.Split(list, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
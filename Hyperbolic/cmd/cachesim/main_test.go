@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Tests that cachesim prints a result for every policy and capacity,
+// and returns errors instead of exiting.
+func Test_Run(t *testing.T) {
+	trace_file := filepath.Join(t.TempDir(), "trace")
+	if err := os.WriteFile(trace_file, []byte("0,a,1,9,1,get,0\n1,a,1,9,1,get,0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout strings.Builder
+	err := run([]string{"-traces", trace_file, "-policies", "FIFO,HYPERBOLIC",
+		"-capacities", "1,2"}, &stdout, io.Discard)
+	if err != nil {
+		t.Errorf("Failed to run: %v", err)
+		t.FailNow()
+	}
+
+	if count := strings.Count(stdout.String(), "Hit Ratio: 0.5 Byte Hit Ratio: 0.5"); count != 4 {
+		t.Errorf("Expected 4 results with hit ratios of 0.5, got %d:\n%s", count, stdout.String())
+		t.FailNow()
+	}
+
+	// with byte capacities, the 10 byte item only fits in the larger cache
+	stdout.Reset()
+	err = run([]string{"-traces", trace_file, "-policies", "LRU,HYPERBOLIC",
+		"-capacities", "5,10", "-byte-capacity"}, &stdout, io.Discard)
+	if err != nil || strings.Count(stdout.String(), "Hit Ratio: 0 Byte Hit Ratio: 0") != 2 ||
+		strings.Count(stdout.String(), "Hit Ratio: 0.5 Byte Hit Ratio: 0.5") != 2 {
+		t.Errorf("Expected 2 results with hit ratios of 0 and 2 of 0.5, got %v:\n%s", err, stdout.String())
+		t.FailNow()
+	}
+
+	// workloads run like trace files
+	stdout.Reset()
+	err = run([]string{"-workloads", "loop:keys=2;scan", "-requests", "4", "-policies", "LRU",
+		"-capacities", "2"}, &stdout, io.Discard)
+	if err != nil || !strings.Contains(stdout.String(), "LRU Hit Ratio: 0.5 ") ||
+		!strings.Contains(stdout.String(), "LRU Hit Ratio: 0 ") {
+		t.Errorf("Expected hit ratios of 0.5 and 0 for the workloads, got %v:\n%s", err, stdout.String())
+		t.FailNow()
+	}
+
+	// results of sampling policies are told apart by the sample
+	// size asked for and seed when there is a choice of them
+	stdout.Reset()
+	err = run([]string{"-workloads", "uniform:keys=10", "-requests", "100", "-policies", "HYPERBOLIC,LRU",
+		"-capacities", "5", "-sample-sizes", "2,5,9", "-seeds", "7", "-workers", "2"}, &stdout, io.Discard)
+	if err != nil || !strings.Contains(stdout.String(), "HYPERBOLIC [sample size 2, seed 7] Hit Ratio:") ||
+		!strings.Contains(stdout.String(), "HYPERBOLIC [sample size 5, seed 7] Hit Ratio:") ||
+		!strings.Contains(stdout.String(), "HYPERBOLIC [sample size 9 capped at 5, seed 7] Hit Ratio:") ||
+		strings.Count(stdout.String(), "\nLRU Hit Ratio:") != 1 {
+		t.Errorf("Expected results for every sample size, got %v:\n%s", err, stdout.String())
+		t.FailNow()
+	}
+
+	// structured results have a row per experiment, failed or not
+	stdout.Reset()
+	err = run([]string{"-workloads", "loop:keys=2", "-requests", "4", "-policies", "LRU,RANDOM",
+		"-capacities", "2", "-output", "csv"}, &stdout, io.Discard)
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if err == nil || len(lines) != 3 || !strings.HasPrefix(lines[1], "loop:keys=2 (seed 1),LRU,2,0,0,0,4,2,2,") {
+		t.Errorf("Expected a header and 2 rows of CSV and an error, got %v:\n%s", err, stdout.String())
+		t.FailNow()
+	}
+
+	// curves of every source are plotted as SVG files
+	plots := filepath.Join(t.TempDir(), "plots")
+	err = run([]string{"-workloads", "loop:keys=2;scan", "-requests", "4", "-policies", "LRU,FIFO",
+		"-capacities", "1,2", "-plots", plots}, io.Discard, io.Discard)
+	if err != nil {
+		t.Errorf("Failed to run with plots: %v", err)
+		t.FailNow()
+	}
+	for _, name := range []string{"loop_keys_2__seed_1_-hit-ratio.svg", "loop_keys_2__seed_1_-miss-ratio.svg",
+		"scan__seed_1_-hit-ratio.svg", "scan__seed_1_-miss-ratio.svg"} {
+		svg, err := os.ReadFile(filepath.Join(plots, name))
+		if err != nil || strings.Count(string(svg), "<polyline") != 2 {
+			t.Errorf("Expected a plot of 2 curves in %s, got %v:\n%s", name, err, svg)
+			t.FailNow()
+		}
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"-traces", trace_file, "-capacities", "many"},
+		{"-traces", trace_file, "-policies", "RANDOM"},
+		{"-traces", trace_file, "-format", "csv"},
+		{"-workloads", "normal"},
+		{"-workloads", "loop", "-output", "xml"},
+		{"-workloads", "loop", "-seeds", "one"},
+		{"-traces", filepath.Join(t.TempDir(), "missing")},
+	} {
+		if err := run(args, io.Discard, io.Discard); err == nil {
+			t.Errorf("Expected an error for arguments %q", args)
+			t.FailNow()
+		}
+	}
+}
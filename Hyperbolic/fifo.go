@@ -6,19 +6,25 @@ import (
 
 // A FIFOCache is a fixed-size, in-memory cache with
 // first-in, first-out eviction.
-type FIFOCache struct {
+type FIFOCache[K comparable, V any] struct {
 
-	// total number of items the FIFOCache can store
+	// total number of items (or bytes, in byte capacity
+	// mode) the FIFOCache can store
 	max_capacity int
 
+	// whether max_capacity counts bytes instead of items
+	by_bytes bool
+
 	// total number of items currently in the FIFOCache
 	size int
 
-	// mapping of keys to items (represented by an arbitrary int)
-	// in the FIFOCache
-	keys_to_items map[string]int
+	// how much of max_capacity the items currently in the FIFOCache use
+	used int
+
+	// mapping of keys to items in the FIFOCache
+	keys_to_items map[K]*list.Element
 
-	// linked list of string keys in the FIFOCache
+	// linked list of items in the FIFOCache, oldest first
 	linked_list *list.List
 
 	// number of hits from the FIFOCache
@@ -26,91 +32,218 @@ type FIFOCache struct {
 
 	// number of misses from the FIFOCache
 	misses int
+
+	// total size of the items hit in the FIFOCache
+	hit_bytes int
+
+	// total size of the items missed in the FIFOCache
+	miss_bytes int
+
+	// number of items evicted from the FIFOCache
+	evictions int
+
+	// clock that tells the FIFOCache when items expire
+	clock Clock
+}
+
+// A FIFOCacheItem holds a key, value pair to be put in a linked list.
+type FIFOCacheItem[K comparable, V any] struct {
+	key        K
+	value      V
+	size       int
+	expires_at int
 }
 
 // NewFIFOCache returns a pointer to a new, empty FIFOCache.
-func NewFIFOCache(max_capacity int) *FIFOCache {
+func NewFIFOCache[K comparable, V any](max_capacity int, opts ...Option) (*FIFOCache[K, V], error) {
+
+	if err := validateCapacity(max_capacity); err != nil {
+		return nil, err
+	}
+
+	config := newOptions(opts)
 
 	// create and initialize a new FIFOCache
-	return &FIFOCache{
+	return &FIFOCache[K, V]{
 		max_capacity:  max_capacity,
+		by_bytes:      config.by_bytes,
+		clock:         config.clock,
 		size:          0,
-		keys_to_items: make(map[string]int, max_capacity),
+		used:          0,
+		keys_to_items: make(map[K]*list.Element),
 		linked_list:   list.New(),
 		hits:          0,
 		misses:        0,
-	}
+	}, nil
 }
 
-// Get returns a success boolean indicating if an item with the
-// key was found in the cache.
-func (fifo *FIFOCache) Get(key string) (success bool) {
+// Get returns the value of the item with the key and a success boolean
+// indicating if the item was found in the cache.
+func (fifo *FIFOCache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	// read the time of this request
+	now := fifo.clock.Now()
 
 	// cache is empty
 	if fifo.size == 0 {
 		fifo.misses++
-		return false
+		fifo.miss_bytes += requestSize(opts)
+		return value, false
 	}
 
 	// check if there is an item with the given key
-	_, ok := fifo.keys_to_items[key]
+	existing_item, ok := fifo.keys_to_items[key]
+
+	// an expired item is removed and counts as a miss
+	if ok && expired(existing_item.Value.(*FIFOCacheItem[K, V]).expires_at, now) {
+		fifo.remove(existing_item)
+		ok = false
+	}
 
 	// update hits/misses
 	if ok {
 		fifo.hits++
 	} else {
 		fifo.misses++
-		return false
+		fifo.miss_bytes += requestSize(opts)
+		return value, false
 	}
 
-	return true
+	item := existing_item.Value.(*FIFOCacheItem[K, V])
+	fifo.hit_bytes += item.size
+
+	return item.value, true
 }
 
-// Set sets the value of the item with the given key to be the given timestamp,
-// possibly evicting an item to make room for a new key insertion.
+// Set sets the value of the item with the given key,
+// possibly evicting items to make room for a new key insertion.
 // Returns true if the item was added/updated successfully, else false.
-// (Note: The assignment is actually not necessary at all.)
-func (fifo *FIFOCache) Set(operation_timestamp int, key string) (success bool) {
+func (fifo *FIFOCache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	// read the time of this request
+	operation_timestamp := fifo.clock.Now()
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
 
 	// can not set if cache max capacity is 0!
 	if fifo.max_capacity == 0 {
-		return false
+		return false, nil
 	}
 
+	item_weight := weight(fifo.by_bytes, description.size)
+
 	// check if there is an existing item with the key
-	_, ok := fifo.keys_to_items[key]
+	existing_item, ok := fifo.keys_to_items[key]
+
+	// an item larger than the whole cache can never fit,
+	// and any older value for its key is now stale
+	if item_weight > fifo.max_capacity {
+		if ok {
+			fifo.remove(existing_item)
+		}
+		return false, nil
+	}
 
 	if ok {
-		fifo.keys_to_items[key] = operation_timestamp
-		return true
+		item := existing_item.Value.(*FIFOCacheItem[K, V])
+		item.value = value
+		item.expires_at = expiry(operation_timestamp, description.ttl)
+
+		// account for a change in size, evicting other
+		// items if the item no longer fits
+		fifo.used += item_weight - weight(fifo.by_bytes, item.size)
+		item.size = description.size
+		fifo.makeRoom(0, existing_item)
+
+		return true, nil
 	}
 
 	// item with the key does not exist, so check if we need to evict
-	if fifo.size == fifo.max_capacity {
+	fifo.makeRoom(item_weight, nil)
+
+	// insert the item into the linked list
+	entry := fifo.linked_list.PushBack(&FIFOCacheItem[K, V]{key: key, value: value,
+		size: description.size, expires_at: expiry(operation_timestamp, description.ttl)})
+	fifo.keys_to_items[key] = entry
+
+	// update the size of the FIFOCache
+	fifo.size++
+	fifo.used += item_weight
+
+	return true, nil
+}
+
+// makeRoom evicts items, oldest first, until an item of the given
+// weight fits in the FIFOCache. The protected item is never evicted.
+func (fifo *FIFOCache[K, V]) makeRoom(item_weight int, protected *list.Element) {
+
+	for fifo.used+item_weight > fifo.max_capacity {
 
 		// remove the first item
 		first := fifo.linked_list.Front()
-		fifo.linked_list.Remove(first)
+		if first == protected {
+			first = first.Next()
+		}
 
-		key_to_remove := first.Value.(string)
+		if first == nil {
+			return
+		}
 
-		// remove the first item from the map
-		delete(fifo.keys_to_items, key_to_remove)
+		fifo.remove(first)
+		fifo.evictions++
+	}
+}
 
-		fifo.size--
+// Delete removes the item with the given key from the FIFOCache.
+// Returns true if the item was found and removed, else false.
+func (fifo *FIFOCache[K, V]) Delete(key K) (success bool) {
+
+	// check if there is an item with the given key
+	existing_item, ok := fifo.keys_to_items[key]
+	if !ok {
+		return false
 	}
 
-	// insert the value into the linked list
-	fifo.linked_list.PushBack(key)
-	fifo.keys_to_items[key] = operation_timestamp
+	fifo.remove(existing_item)
+
+	return true
+}
+
+// remove unlinks an item from the linked list and the map.
+func (fifo *FIFOCache[K, V]) remove(element *list.Element) {
+
+	item := fifo.linked_list.Remove(element).(*FIFOCacheItem[K, V])
+	delete(fifo.keys_to_items, item.key)
 
 	// update the size of the FIFOCache
-	fifo.size++
+	fifo.size--
+	fifo.used -= weight(fifo.by_bytes, item.size)
+}
 
-	return true
+// Contains returns true if an item with the key is in the FIFOCache,
+// whether or not it has expired, without counting as a use.
+func (fifo *FIFOCache[K, V]) Contains(key K) bool {
+	_, ok := fifo.keys_to_items[key]
+	return ok
+}
+
+// Victim returns the key of the item the FIFOCache would evict first to
+// make room for a new item of the given size, and true, or false if
+// the item fits without evicting anything.
+func (fifo *FIFOCache[K, V]) Victim(size int) (key K, ok bool) {
+
+	if fifo.used+weight(fifo.by_bytes, size) <= fifo.max_capacity || fifo.linked_list.Len() == 0 {
+		return key, false
+	}
+
+	return fifo.linked_list.Front().Value.(*FIFOCacheItem[K, V]).key, true
 }
 
 // Stats returns statistics about how many search hits and misses have occurred.
-func (fifo *FIFOCache) Stats() *Stats {
-	return &Stats{Hits: fifo.hits, Misses: fifo.misses}
+func (fifo *FIFOCache[K, V]) Stats() *Stats {
+	return &Stats{Hits: fifo.hits, Misses: fifo.misses,
+		HitBytes: fifo.hit_bytes, MissBytes: fifo.miss_bytes, Evictions: fifo.evictions}
 }
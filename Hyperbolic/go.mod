@@ -1,3 +1,3 @@
 module github.com/jimmytienhoangy/COS316_Project
 
-go 1.17
+go 1.21
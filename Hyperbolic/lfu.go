@@ -2,41 +2,65 @@ package cache
 
 import (
 	"container/list"
+	"fmt"
 )
 
-// A LFUCacheItem is an item with metadata that implicitly
-// holds a value of set size. It goes in a LFUCache.
-type LFUCacheItem struct {
+// A LFUCacheItem is an item with metadata that
+// holds a value. It goes in a LFUCache.
+type LFUCacheItem[K comparable, V any] struct {
 
 	// the item's key
-	key string
+	key K
+
+	// the item's value
+	value V
+
+	// the item's size
+	size int
+
+	// when the item expires, or 0 if it never expires
+	expires_at int
 
 	// pointer to a struct indicating this item's access count
 	accessParent *list.Element
+
+	// the item's place among the items with its access count
+	accessEntry *list.Element
+
+	// number of times the item has been set or hit
+	frequency int
 }
 
 // An AccessNode is the connection between an access count and
 // the items with that access count.
-type AccessNode struct {
+type AccessNode[K comparable, V any] struct {
 
-	// items with this access item's access count
-	items_with_access_count map[*LFUCacheItem]byte
+	// items with this access item's access count,
+	// least recently accessed first
+	items_with_access_count *list.List
 
 	// the access count associated with this access item
 	access_count int
 }
 
 // A LFUCache is a cache that uses lfu caching.
-type LFUCache struct {
+type LFUCache[K comparable, V any] struct {
 
-	// maximum number of items the cache can hold
+	// maximum number of items (or bytes, in byte
+	// capacity mode) the cache can hold
 	max_capacity int
 
+	// whether max_capacity counts bytes instead of items
+	by_bytes bool
+
 	// total number of items currently in the cache
 	size int
 
+	// how much of max_capacity the items currently in the cache use
+	used int
+
 	// map of keys to items in the cache
-	keys_to_items map[string]*LFUCacheItem
+	keys_to_items map[K]*LFUCacheItem[K, V]
 
 	// linked list of access counts
 	access_counts *list.List
@@ -46,71 +70,158 @@ type LFUCache struct {
 
 	// number of misses
 	misses int
+
+	// total size of the items hit
+	hit_bytes int
+
+	// total size of the items missed
+	miss_bytes int
+
+	// number of items evicted
+	evictions int
+
+	// clock that tells the cache when items expire
+	clock Clock
+
+	// number of accesses after which every access count is
+	// halved, or 0 to never halve them
+	halving_period int
+
+	// number of accesses since access counts were last halved
+	accesses int
+
+	// whether access counts include the cache's age (LFU-DA)
+	dynamic_aging bool
+
+	// access count of the last evicted item, in LFU-DA
+	age int
 }
 
 // NewLFUCache creates a new, empty LFUCache.
-func NewLFUCache(max_capacity int) *LFUCache {
+func NewLFUCache[K comparable, V any](max_capacity int, opts ...Option) (*LFUCache[K, V], error) {
+
+	if err := validateCapacity(max_capacity); err != nil {
+		return nil, err
+	}
 
-	return &LFUCache{
-		max_capacity:  max_capacity,
-		size:          0,
-		keys_to_items: make(map[string]*LFUCacheItem, max_capacity),
-		access_counts: list.New(),
-		hits:          0,
-		misses:        0,
+	config := newOptions(opts)
+
+	if config.halving_period < 0 {
+		return nil, fmt.Errorf("%w: halving period %d: %w",
+			ErrInvalidAging, config.halving_period, ErrNegativeValue)
+	}
+
+	if config.halving_period > 0 && config.dynamic_aging {
+		return nil, fmt.Errorf("%w: halving access counts can not be combined with dynamic aging",
+			ErrInvalidAging)
 	}
+
+	return &LFUCache[K, V]{
+		max_capacity:   max_capacity,
+		by_bytes:       config.by_bytes,
+		clock:          config.clock,
+		halving_period: config.halving_period,
+		dynamic_aging:  config.dynamic_aging,
+		size:           0,
+		used:           0,
+		keys_to_items:  make(map[K]*LFUCacheItem[K, V]),
+		access_counts:  list.New(),
+		hits:           0,
+		misses:         0,
+	}, nil
 }
 
-// Get returns a success boolean indicating if an item with the key was found.
-func (cache *LFUCache) Get(key string) (success bool) {
+// Get returns the value of the item with the key and a success boolean
+// indicating if the item was found.
+func (cache *LFUCache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	// read the time of this request
+	now := cache.clock.Now()
 
 	// retrieve item associated with key
 	item, ok := cache.keys_to_items[key]
 
+	// an expired item is removed and counts as a miss
+	if ok && expired(item.expires_at, now) {
+		cache.discard(item)
+		ok = false
+	}
+
 	if ok {
 		cache.hits += 1
+		cache.hit_bytes += item.size
 
 		// update access count of item
 		cache.increment(item)
 
 	} else {
 		cache.misses += 1
+		cache.miss_bytes += requestSize(opts)
 
-		return false
+		return value, false
 	}
 
-	return true
+	return item.value, true
 }
 
-// Set adds/updates an item with the given key in the cache
+// Set adds/updates an item with the given key and value in the cache
 // and returns a success boolean.
-func (lfu *LFUCache) Set(operation_timestamp int, key string) (success bool) {
+func (lfu *LFUCache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	// read the time of this request
+	operation_timestamp := lfu.clock.Now()
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
 
 	// can not set if cache max capacity is 0!
 	if lfu.max_capacity == 0 {
-		return false
+		return false, nil
 	}
 
-	// operation_timestamp is ignored for the purposes of this project
+	item_weight := weight(lfu.by_bytes, description.size)
 
 	// check if an item with that key already exists
 	existing_item, ok := lfu.keys_to_items[key]
 
+	// an item larger than the whole cache can never fit,
+	// and any older value for its key is now stale
+	if item_weight > lfu.max_capacity {
+		if ok {
+			lfu.discard(existing_item)
+		}
+		return false, nil
+	}
+
 	if ok {
+		existing_item.value = value
+		existing_item.expires_at = expiry(operation_timestamp, description.ttl)
+
 		// update access count of item
 		lfu.increment(existing_item)
 
-		return true
+		// account for a change in size, evicting other
+		// items if the item no longer fits
+		lfu.used += item_weight - weight(lfu.by_bytes, existing_item.size)
+		existing_item.size = description.size
+		if err := lfu.makeRoom(0, existing_item); err != nil {
+			return false, err
+		}
+
+		return true, nil
 	}
 
 	// if not enough space and an item with the key does not exist,
-	// evict an item
-	if lfu.size == lfu.max_capacity {
-		lfu.evict()
+	// evict items
+	if err := lfu.makeRoom(item_weight, nil); err != nil {
+		return false, err
 	}
 
 	// add new item with key
-	new_item := &LFUCacheItem{key: key}
+	new_item := &LFUCacheItem[K, V]{key: key, value: value, size: description.size,
+		expires_at: expiry(operation_timestamp, description.ttl)}
 	lfu.keys_to_items[key] = new_item
 
 	// update access for the new item
@@ -118,16 +229,32 @@ func (lfu *LFUCache) Set(operation_timestamp int, key string) (success bool) {
 
 	// update size of cache
 	lfu.size += 1
+	lfu.used += item_weight
 
-	return true
+	return true, nil
+}
+
+// makeRoom evicts the least frequently used items, one at a time, until an item of the
+// given weight fits in the cache. The protected item is never evicted.
+func (lfu *LFUCache[K, V]) makeRoom(item_weight int, protected *LFUCacheItem[K, V]) error {
+
+	for lfu.used+item_weight > lfu.max_capacity {
+		if err := lfu.evict(protected); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // Increment updates the access count of a given item.
-func (lfu *LFUCache) increment(item *LFUCacheItem) {
+func (lfu *LFUCache[K, V]) increment(item *LFUCacheItem[K, V]) {
 
 	// check if the item is already associated with an access count node
 	currentAccessNode := item.accessParent
 
+	item.frequency += 1
+
 	// find new access count value and corresponding node
 	var nextAccessCount int
 	var nextAccessNode *list.Element
@@ -136,82 +263,204 @@ func (lfu *LFUCache) increment(item *LFUCacheItem) {
 	if currentAccessNode == nil {
 		// first access
 		nextAccessCount = 1
-		// item's access count node should be the very front (1st!)
+		// item's access count node should be at the very front (1st!)
 		nextAccessNode = lfu.access_counts.Front()
 	} else {
 		// increment access count by 1
-		nextAccessCount = currentAccessNode.Value.(*AccessNode).access_count + 1
+		nextAccessCount = currentAccessNode.Value.(*AccessNode[K, V]).access_count + 1
 
 		// move to next access count node (that may not exist)
 		nextAccessNode = currentAccessNode.Next()
 	}
 
+	// with dynamic aging, the access count starts from the cache's age
+	// instead, so the item's node may be further along the list
+	previousAccessNode := currentAccessNode
+	if lfu.dynamic_aging {
+		nextAccessCount = lfu.age + item.frequency
+
+		for nextAccessNode != nil && nextAccessNode.Value.(*AccessNode[K, V]).access_count < nextAccessCount {
+			previousAccessNode = nextAccessNode
+			nextAccessNode = nextAccessNode.Next()
+		}
+	}
+
 	// next access count node does not exist or there is a gap:
 	// for example, a key has 6 accesses and another with 8 accesses, but no key has 7 accesses anymore
-	if nextAccessNode == nil || nextAccessNode.Value.(*AccessNode).access_count != nextAccessCount {
+	if nextAccessNode == nil || nextAccessNode.Value.(*AccessNode[K, V]).access_count != nextAccessCount {
 
 		// create a new access count node for the missing access count
-		newAccessNode := new(AccessNode)
+		newAccessNode := new(AccessNode[K, V])
 		newAccessNode.access_count = nextAccessCount
-		newAccessNode.items_with_access_count = make(map[*LFUCacheItem]byte)
+		newAccessNode.items_with_access_count = list.New()
 
-		// add new access count node to the front if this is a new insert
-		if currentAccessNode == nil {
+		// add new access count node to the front if no node comes before it
+		if previousAccessNode == nil {
 			nextAccessNode = lfu.access_counts.PushFront(newAccessNode)
 
 		} else {
-			// add new access count node after the current one
-			nextAccessNode = lfu.access_counts.InsertAfter(newAccessNode, currentAccessNode)
+			// add new access count node after the one before it
+			nextAccessNode = lfu.access_counts.InsertAfter(newAccessNode, previousAccessNode)
 		}
 	}
 
-	// set the new access count parent for the item that is being incremented and
-	// add it to that parent's list of entries
-	item.accessParent = nextAccessNode
-	nextAccessNode.Value.(*AccessNode).items_with_access_count[item] = 1
-
 	// remove the item from the entries of its old access count node (currentAccessNode)
 	if currentAccessNode != nil {
 		lfu.remove(currentAccessNode, item)
 	}
+
+	// set the new access count parent for the item that is being incremented and
+	// add it to the back of that parent's list of entries, as it is the most
+	// recently accessed
+	item.accessParent = nextAccessNode
+	item.accessEntry = nextAccessNode.Value.(*AccessNode[K, V]).items_with_access_count.PushBack(item)
+
+	// periodically halve every access count
+	lfu.accesses += 1
+	if lfu.halving_period > 0 && lfu.accesses >= lfu.halving_period {
+		lfu.halve()
+		lfu.accesses = 0
+	}
 }
 
-// evict evicts the least frequently used item from the cache.
-func (lfu *LFUCache) evict() {
+// halve halves the access count of every item, rounding up so that no
+// access count drops to 0. Access count nodes that end up with the same
+// access count are merged, keeping the items that had the smaller
+// access count in front.
+func (lfu *LFUCache[K, V]) halve() {
+
+	var previousAccessNode *list.Element
+
+	for accessNode := lfu.access_counts.Front(); accessNode != nil; {
+		nextAccessNode := accessNode.Next()
 
-	// get the smallest access count node
-	if smallestAccessNode := lfu.access_counts.Front(); smallestAccessNode != nil {
+		node := accessNode.Value.(*AccessNode[K, V])
+		node.access_count -= node.access_count / 2
 
-		// for all the entries of this access count node
-		for entry := range smallestAccessNode.Value.(*AccessNode).items_with_access_count {
+		// merge this node into the one before it if their access counts now match
+		if previousAccessNode != nil &&
+			previousAccessNode.Value.(*AccessNode[K, V]).access_count == node.access_count {
 
-			// delete the item from the cache
-			delete(lfu.keys_to_items, entry.key)
+			previous := previousAccessNode.Value.(*AccessNode[K, V])
+			for entry := node.items_with_access_count.Front(); entry != nil; entry = node.items_with_access_count.Front() {
+				item := node.items_with_access_count.Remove(entry).(*LFUCacheItem[K, V])
+				item.accessParent = previousAccessNode
+				item.accessEntry = previous.items_with_access_count.PushBack(item)
+			}
 
-			// remove the item from all lists
-			lfu.remove(smallestAccessNode, entry)
+			lfu.access_counts.Remove(accessNode)
+		} else {
+			previousAccessNode = accessNode
+		}
+
+		accessNode = nextAccessNode
+	}
+}
+
+// evict evicts the least frequently used item from the cache, other
+// than the protected item. Ties between items with the same access count
+// go to the least recently accessed item. An error is returned if the
+// cache has no access counts with items to evict.
+func (lfu *LFUCache[K, V]) evict(protected *LFUCacheItem[K, V]) error {
+
+	// start from the smallest access count node
+	for accessNode := lfu.access_counts.Front(); accessNode != nil; accessNode = accessNode.Next() {
+
+		// the least recently accessed entry of this access count node
+		entry := accessNode.Value.(*AccessNode[K, V]).items_with_access_count.Front()
+		if entry != nil && entry.Value.(*LFUCacheItem[K, V]) == protected {
+			entry = entry.Next()
+		}
+
+		if entry == nil {
+			continue
+		}
 
-			lfu.size--
+		// with dynamic aging, the cache's age becomes the evicted item's access count
+		if lfu.dynamic_aging {
+			lfu.age = accessNode.Value.(*AccessNode[K, V]).access_count
 		}
+
+		// delete the item from the cache and remove it from all lists
+		lfu.discard(entry.Value.(*LFUCacheItem[K, V]))
+		lfu.evictions++
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d items but no access counts to evict from",
+		ErrInconsistentState, lfu.size)
+}
+
+// Delete removes the item with the given key from the cache.
+// Returns true if the item was found and removed, else false.
+func (lfu *LFUCache[K, V]) Delete(key K) (success bool) {
+
+	// check if there is an item with the given key
+	item, ok := lfu.keys_to_items[key]
+	if !ok {
+		return false
 	}
+
+	lfu.discard(item)
+
+	return true
 }
 
-// Remove removes the item associated with the given key from the cache, if it exists.
-func (lfu *LFUCache) remove(listItem *list.Element, item *LFUCacheItem) {
+// discard deletes an item from the cache.
+func (lfu *LFUCache[K, V]) discard(item *LFUCacheItem[K, V]) {
+
+	// delete the item from the cache
+	delete(lfu.keys_to_items, item.key)
 
-	accessNode := listItem.Value.(*AccessNode)
+	// remove the item from its access count node, dropping the node if
+	// it is now empty
+	lfu.remove(item.accessParent, item)
+
+	lfu.size--
+	lfu.used -= weight(lfu.by_bytes, item.size)
+}
+
+// remove removes the item from the entries of the given access count node,
+// removing the node itself once it no longer has entries.
+func (lfu *LFUCache[K, V]) remove(listItem *list.Element, item *LFUCacheItem[K, V]) {
+
+	accessNode := listItem.Value.(*AccessNode[K, V])
 
 	// remove the item from its corresponding access count node's entries
-	delete(accessNode.items_with_access_count, item)
+	accessNode.items_with_access_count.Remove(item.accessEntry)
 
 	// this access node no longer has entries, so remove it from the list
 	// of access counts
-	if len(accessNode.items_with_access_count) == 0 {
+	if accessNode.items_with_access_count.Len() == 0 {
 		lfu.access_counts.Remove(listItem)
 	}
 }
 
+// Contains returns true if an item with the key is in the LFUCache,
+// whether or not it has expired, without counting as a use.
+func (lfu *LFUCache[K, V]) Contains(key K) bool {
+	_, ok := lfu.keys_to_items[key]
+	return ok
+}
+
+// Victim returns the key of the item the LFUCache would evict first to
+// make room for a new item of the given size, and true, or false if
+// the item fits without evicting anything.
+func (lfu *LFUCache[K, V]) Victim(size int) (key K, ok bool) {
+
+	if lfu.used+weight(lfu.by_bytes, size) <= lfu.max_capacity || lfu.access_counts.Len() == 0 {
+		return key, false
+	}
+
+	// access count nodes are dropped once they have no entries, so the
+	// smallest access count node has the victim
+	entries := lfu.access_counts.Front().Value.(*AccessNode[K, V]).items_with_access_count
+	return entries.Front().Value.(*LFUCacheItem[K, V]).key, true
+}
+
 // Stats returns statistics about how many search hits and misses have occurred.
-func (lfu *LFUCache) Stats() *Stats {
-	return &Stats{Hits: lfu.hits, Misses: lfu.misses}
+func (lfu *LFUCache[K, V]) Stats() *Stats {
+	return &Stats{Hits: lfu.hits, Misses: lfu.misses,
+		HitBytes: lfu.hit_bytes, MissBytes: lfu.miss_bytes, Evictions: lfu.evictions}
 }
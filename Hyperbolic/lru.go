@@ -6,18 +6,25 @@ import (
 
 // A LRUCache is a fixed-size, in-memory cache with
 // least-recently-used eviction.
-type LRUCache struct {
+type LRUCache[K comparable, V any] struct {
 
-	// total number of items the LRUCache can store
+	// total number of items (or bytes, in byte capacity
+	// mode) the LRUCache can store
 	max_capacity int
 
+	// whether max_capacity counts bytes instead of items
+	by_bytes bool
+
 	// total number of items currently in the LRUCache
 	size int
 
+	// how much of max_capacity the items currently in the LRUCache use
+	used int
+
 	// mapping of keys to items in the LRUCache
-	keys_to_items map[string]*list.Element
+	keys_to_items map[K]*list.Element
 
-	// linked list of string keys in the LRUCache
+	// linked list of items in the LRUCache, least recently used first
 	linked_list *list.List
 
 	// number of hits from the LRUCache
@@ -25,41 +32,75 @@ type LRUCache struct {
 
 	// number of misses from the LRUCache
 	misses int
+
+	// total size of the items hit in the LRUCache
+	hit_bytes int
+
+	// total size of the items missed in the LRUCache
+	miss_bytes int
+
+	// number of items evicted from the LRUCache
+	evictions int
+
+	// clock that tells the LRUCache when items expire
+	clock Clock
 }
 
 // A LRUCacheItem holds a key, value pair to be put in a linked list.
-type LRUCacheItem struct {
-	key   string
-	value int
+type LRUCacheItem[K comparable, V any] struct {
+	key        K
+	value      V
+	size       int
+	expires_at int
 }
 
 // NewLRU returns a pointer to a new, empty LRUCache.
-func NewLRUCache(max_capacity int) *LRUCache {
+func NewLRUCache[K comparable, V any](max_capacity int, opts ...Option) (*LRUCache[K, V], error) {
+
+	if err := validateCapacity(max_capacity); err != nil {
+		return nil, err
+	}
+
+	config := newOptions(opts)
 
 	// create and initialize a new LRUCache
-	return &LRUCache{
+	return &LRUCache[K, V]{
 		max_capacity:  max_capacity,
+		by_bytes:      config.by_bytes,
+		clock:         config.clock,
 		size:          0,
-		keys_to_items: make(map[string]*list.Element, max_capacity),
+		used:          0,
+		keys_to_items: make(map[K]*list.Element),
 		linked_list:   list.New(),
 		hits:          0,
 		misses:        0,
-	}
+	}, nil
 }
 
-// Get returns a success boolean indicating if an item with the key was found.
+// Get returns the value of the item with the key and a success boolean
+// indicating if the item was found.
 // This operation counts as a "use" for that item.
-func (lru *LRUCache) Get(key string) (success bool) {
+func (lru *LRUCache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	// read the time of this request
+	now := lru.clock.Now()
 
 	// cache is empty
 	if lru.size == 0 {
 		lru.misses++
-		return false
+		lru.miss_bytes += requestSize(opts)
+		return value, false
 	}
 
 	// check if there is an item with the given key
 	existing_item, ok := lru.keys_to_items[key]
 
+	// an expired item is removed and counts as a miss
+	if ok && expired(existing_item.Value.(*LRUCacheItem[K, V]).expires_at, now) {
+		lru.remove(existing_item)
+		ok = false
+	}
+
 	// update hits/misses and possibly update most recently used
 	if ok {
 		lru.hits++
@@ -69,68 +110,153 @@ func (lru *LRUCache) Get(key string) (success bool) {
 
 	} else {
 		lru.misses++
-		return false
+		lru.miss_bytes += requestSize(opts)
+		return value, false
 	}
 
-	return true
+	item := existing_item.Value.(*LRUCacheItem[K, V])
+	lru.hit_bytes += item.size
+
+	return item.value, true
 }
 
-// Set sets the value of the item with the given key to be the given timestamp,
-// possibly evicting an item to make room for a new key insertion.
+// Set sets the value of the item with the given key,
+// possibly evicting items to make room for a new key insertion.
 // This operation counts as a "use" for that item.
 // Returns true if the item was added/updated successfully, else false.
-// (Note: The assignment is actually not necessary at all.)
-func (lru *LRUCache) Set(operation_timestamp int, key string) (success bool) {
+func (lru *LRUCache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	// read the time of this request
+	operation_timestamp := lru.clock.Now()
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
 
 	// can not set if cache max capacity is 0!
 	if lru.max_capacity == 0 {
-		return false
+		return false, nil
 	}
 
+	item_weight := weight(lru.by_bytes, description.size)
+
 	// check if there is an existing item with the key
 	existing_item, ok := lru.keys_to_items[key]
 
+	// an item larger than the whole cache can never fit,
+	// and any older value for its key is now stale
+	if item_weight > lru.max_capacity {
+		if ok {
+			lru.remove(existing_item)
+		}
+		return false, nil
+	}
+
 	if ok {
-		existing_item.Value.(*list.Element).Value.(*LRUCacheItem).value = operation_timestamp
+		item := existing_item.Value.(*LRUCacheItem[K, V])
+		item.value = value
+		item.expires_at = expiry(operation_timestamp, description.ttl)
 
 		// move the item to the back
 		lru.linked_list.MoveToBack(existing_item)
 
-		return true
+		// account for a change in size, evicting other
+		// items if the item no longer fits
+		lru.used += item_weight - weight(lru.by_bytes, item.size)
+		item.size = description.size
+		lru.makeRoom(0, existing_item)
+
+		return true, nil
 	}
 
 	// item with the key does not exist, so check if we need to evict
-	if lru.size == lru.max_capacity {
+	lru.makeRoom(item_weight, nil)
+
+	// insert the item into the linked list
+	entry := lru.linked_list.PushBack(&LRUCacheItem[K, V]{key: key, value: value,
+		size: description.size, expires_at: expiry(operation_timestamp, description.ttl)})
+
+	// store the mapping of the pointer of the item into the mapping
+	lru.keys_to_items[key] = entry
+
+	// update the current size of the LRUCache
+	lru.size += 1
+	lru.used += item_weight
+
+	return true, nil
+}
+
+// makeRoom evicts items, least recently used first, until an item of
+// the given weight fits in the LRUCache. The protected item is never
+// evicted.
+func (lru *LRUCache[K, V]) makeRoom(item_weight int, protected *list.Element) {
+
+	for lru.used+item_weight > lru.max_capacity {
 
 		// remove the first item from the linked list
 		first := lru.linked_list.Front()
-		value := lru.linked_list.Remove(first)
+		if first == protected {
+			first = first.Next()
+		}
 
-		key_to_remove := value.(*list.Element).Value.(*LRUCacheItem).key
+		if first == nil {
+			return
+		}
 
-		// remove the first item from the map
-		delete(lru.keys_to_items, key_to_remove)
+		lru.remove(first)
+		lru.evictions++
+	}
+}
 
-		// update the current size of the cache
-		lru.size -= 1
+// Delete removes the item with the given key from the LRUCache.
+// Returns true if the item was found and removed, else false.
+func (lru *LRUCache[K, V]) Delete(key K) (success bool) {
 
+	// check if there is an item with the given key
+	existing_item, ok := lru.keys_to_items[key]
+	if !ok {
+		return false
 	}
 
-	// insert the item into the linked list
-	item_to_add := &list.Element{Value: &LRUCacheItem{key: key, value: operation_timestamp}}
-	entry := lru.linked_list.PushBack(item_to_add)
+	lru.remove(existing_item)
 
-	// store the mapping of the pointer of the item into the mapping
-	lru.keys_to_items[key] = entry
+	return true
+}
+
+// remove unlinks an item from the linked list and the map.
+func (lru *LRUCache[K, V]) remove(element *list.Element) {
+
+	item := lru.linked_list.Remove(element).(*LRUCacheItem[K, V])
+	delete(lru.keys_to_items, item.key)
 
 	// update the current size of the LRUCache
-	lru.size += 1
+	lru.size -= 1
+	lru.used -= weight(lru.by_bytes, item.size)
+}
 
-	return true
+// Contains returns true if an item with the key is in the LRUCache,
+// whether or not it has expired, without counting as a use.
+func (lru *LRUCache[K, V]) Contains(key K) bool {
+	_, ok := lru.keys_to_items[key]
+	return ok
+}
+
+// Victim returns the key of the item the LRUCache would evict first to
+// make room for a new item of the given size, and true, or false if
+// the item fits without evicting anything.
+func (lru *LRUCache[K, V]) Victim(size int) (key K, ok bool) {
+
+	if lru.used+weight(lru.by_bytes, size) <= lru.max_capacity || lru.linked_list.Len() == 0 {
+		return key, false
+	}
+
+	return lru.linked_list.Front().Value.(*LRUCacheItem[K, V]).key, true
 }
 
 // Stats returns statistics about how many search hits and misses have
 // occurred in the LRUCache.
-func (lru *LRUCache) Stats() *Stats {
-	return &Stats{Hits: lru.hits, Misses: lru.misses}
+func (lru *LRUCache[K, V]) Stats() *Stats {
+	return &Stats{Hits: lru.hits, Misses: lru.misses,
+		HitBytes: lru.hit_bytes, MissBytes: lru.miss_bytes, Evictions: lru.evictions}
 }
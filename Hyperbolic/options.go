@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// An Option changes how a cache is configured when it is created.
+type Option func(*options)
+
+// options holds the configuration that can be changed with Options.
+type options struct {
+
+	// source of randomness for sampling eviction
+	source rand.Source
+
+	// number of eviction candidates a sampling cache carries
+	// over from one eviction to the next
+	retained_candidates int
+
+	// whether max capacity counts bytes instead of items
+	by_bytes bool
+
+	// clock that tells the cache the time
+	clock Clock
+
+	// number of accesses after which a LFU cache halves
+	// every access count, or 0 to never halve them
+	halving_period int
+
+	// whether a LFU cache uses dynamic aging (LFU-DA)
+	dynamic_aging bool
+}
+
+// newOptions returns the default configuration with the given Options applied.
+func newOptions(opts []Option) *options {
+
+	config := &options{}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	// seed from the current time unless a source was given
+	if config.source == nil {
+		config.source = rand.NewSource(time.Now().UnixNano())
+	}
+
+	// read the system time unless a clock was given
+	if config.clock == nil {
+		config.clock = WallClock{}
+	}
+
+	return config
+}
+
+// WithByteCapacity makes a cache's max capacity count the total size
+// of its items, given to Set with WithSize, instead of their number.
+// The cache evicts items until a new item's size fits.
+func WithByteCapacity() Option {
+	return func(config *options) {
+		config.by_bytes = true
+	}
+}
+
+// WithClock makes a cache read the time from the given clock, which
+// decides when items expire and, for caches whose eviction depends on
+// time, which items are evicted. Caches read the system time by default.
+func WithClock(clock Clock) Option {
+	return func(config *options) {
+		config.clock = clock
+	}
+}
+
+// WithDynamicAging makes a LFU cache use LFU with dynamic aging
+// (LFU-DA): the cache keeps an age, the access count of the last item
+// it evicted, and adds it to the access count of every item when the
+// item is set or hit. Items that were popular long ago are eventually
+// outranked by newer items, which start from the cache's current age.
+func WithDynamicAging() Option {
+	return func(config *options) {
+		config.dynamic_aging = true
+	}
+}
+
+// WithHalvingPeriod makes a LFU cache halve the access count of every
+// item, rounding up, after every halving_period accesses (sets and hits),
+// so that items that are no longer popular can be evicted. A period of
+// 0, the default, never halves access counts. It can not be combined
+// with WithDynamicAging.
+func WithHalvingPeriod(halving_period int) Option {
+	return func(config *options) {
+		config.halving_period = halving_period
+	}
+}
+
+// WithRetainedCandidates makes a sampling cache keep the best (lowest
+// priority) candidates that survive an eviction and add them to the
+// sample of the next eviction, which improves how closely sampling
+// approximates evicting the lowest priority item in the whole cache.
+func WithRetainedCandidates(retained_candidates int) Option {
+	return func(config *options) {
+		config.retained_candidates = retained_candidates
+	}
+}
+
+// WithSource makes a sampling cache draw its eviction samples from the
+// given source. Seeding the source makes evictions reproducible.
+func WithSource(source rand.Source) Option {
+	return func(config *options) {
+		config.source = source
+	}
+}
+
+// An ItemOption describes a single item given to Set.
+type ItemOption func(*item_options)
+
+// item_options holds the description of an item that
+// can be changed with ItemOptions.
+type item_options struct {
+
+	// cost of fetching the item again after a miss
+	cost float64
+
+	// size of the item
+	size int
+
+	// time to live of the item, or 0 if it never expires
+	ttl int
+}
+
+// newItemOptions returns the default item description with the given
+// ItemOptions applied, or an error if any of them is invalid.
+func newItemOptions(opts []ItemOption) (item_options, error) {
+
+	// items cost 1 and have size 1 unless told otherwise
+	description := item_options{cost: 1, size: 1}
+
+	for _, opt := range opts {
+		opt(&description)
+	}
+
+	if description.cost < 0 {
+		return description, fmt.Errorf("%w: cost %g: %w",
+			ErrInvalidItemOption, description.cost, ErrNegativeValue)
+	}
+
+	// a cost of 0 (or NaN) would make priorities that weigh
+	// items by their cost impossible to compare
+	if !(description.cost > 0) {
+		return description, fmt.Errorf("%w: cost must be positive", ErrInvalidItemOption)
+	}
+
+	if description.size < 0 {
+		return description, fmt.Errorf("%w: size %d: %w",
+			ErrInvalidItemOption, description.size, ErrNegativeValue)
+	}
+
+	if description.size == 0 {
+		return description, fmt.Errorf("%w: size must be positive", ErrInvalidItemOption)
+	}
+
+	if description.ttl < 0 {
+		return description, fmt.Errorf("%w: ttl %d: %w",
+			ErrInvalidItemOption, description.ttl, ErrNegativeValue)
+	}
+
+	return description, nil
+}
+
+// WithCost sets the cost of fetching an item again after it misses,
+// such as the time it takes to recompute it. Cost-aware caches hold on
+// to expensive items for longer. Costs must be positive, and items cost 1
+// by default.
+func WithCost(cost float64) ItemOption {
+	return func(description *item_options) {
+		description.cost = cost
+	}
+}
+
+// WithSize sets the size of an item in bytes. Caches created with
+// WithByteCapacity count it against their max capacity, and size-aware
+// caches prefer to evict large items. Given to Get, it is the size of
+// the requested item, counted in Stats if the item is missed.
+// Items have size 1 by default.
+func WithSize(size int) ItemOption {
+	return func(description *item_options) {
+		description.size = size
+	}
+}
+
+// WithTTL sets how long after it is set an item expires, in the units
+// of the cache's Clock. Get treats expired items as misses and removes
+// them. Items never expire by default, or with a TTL of 0.
+func WithTTL(ttl int) ItemOption {
+	return func(description *item_options) {
+		description.ttl = ttl
+	}
+}
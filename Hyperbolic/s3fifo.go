@@ -0,0 +1,417 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// percentage of a S3FIFOCache's max capacity used by its small queue
+const s3fifo_small_percent = 10
+
+// largest access frequency a S3FIFOCache counts for an item
+const s3fifo_max_frequency = 3
+
+// largest number of items of each queue a S3FIFOCache looks at to find
+// its victim, so that finding it does not take longer with more items
+const s3fifo_victim_scan = 16
+
+// A S3FIFOCache is a fixed-size, in-memory cache with S3-FIFO eviction.
+// It is made of three FIFO queues: new items enter a small queue, and
+// most of them, which are never used again, are quickly evicted from
+// it. Items that were used while in the small queue move to the main
+// queue instead, and evicted items are remembered in a ghost queue so
+// that they go straight to the main queue if they are set again. The
+// main queue reinserts items that were used since they were last
+// reinserted instead of evicting them, like a CLOCK.
+type S3FIFOCache[K comparable, V any] struct {
+
+	// total number of items (or bytes, in byte capacity
+	// mode) the S3FIFOCache can store
+	max_capacity int
+
+	// whether max_capacity counts bytes instead of items
+	by_bytes bool
+
+	// how much of max_capacity the small queue uses before
+	// items are evicted from it instead of the main queue
+	small_capacity int
+
+	// new items, oldest first, evicted to ghost
+	small segment
+
+	// items used while in small or set again while in ghost,
+	// oldest first
+	main segment
+
+	// keys recently evicted from small, without their
+	// values, oldest first
+	ghost segment
+
+	// mapping of keys to items in any of the queues
+	keys_to_items map[K]*list.Element
+
+	// number of hits from the S3FIFOCache
+	hits int
+
+	// number of misses from the S3FIFOCache
+	misses int
+
+	// total size of the items hit in the S3FIFOCache
+	hit_bytes int
+
+	// total size of the items missed in the S3FIFOCache
+	miss_bytes int
+
+	// number of items evicted from the S3FIFOCache
+	evictions int
+
+	// clock that tells the S3FIFOCache when items expire
+	clock Clock
+}
+
+// A S3FIFOCacheItem holds a key, value pair to be put in one of
+// the queues of a S3FIFOCache.
+type S3FIFOCacheItem[K comparable, V any] struct {
+	key        K
+	value      V
+	size       int
+	expires_at int
+
+	// number of uses, up to s3fifo_max_frequency, since the item
+	// was set or last reinserted in the main queue
+	frequency int
+
+	// queue the item is in
+	location *segment
+}
+
+// NewS3FIFOCache returns a pointer to a new, empty S3FIFOCache.
+func NewS3FIFOCache[K comparable, V any](max_capacity int, opts ...Option) (*S3FIFOCache[K, V], error) {
+
+	if err := validateCapacity(max_capacity); err != nil {
+		return nil, err
+	}
+
+	config := newOptions(opts)
+
+	// create and initialize a new S3FIFOCache
+	return &S3FIFOCache[K, V]{
+		max_capacity:   max_capacity,
+		by_bytes:       config.by_bytes,
+		clock:          config.clock,
+		small_capacity: max(max_capacity*s3fifo_small_percent/100, 1),
+		small:          newSegment(),
+		main:           newSegment(),
+		ghost:          newSegment(),
+		keys_to_items:  make(map[K]*list.Element),
+		hits:           0,
+		misses:         0,
+	}, nil
+}
+
+// Get returns the value of the item with the key and a success boolean
+// indicating if the item was found in the cache. A key that is only
+// remembered in the ghost queue is a miss.
+// This operation counts as a "use" for that item.
+func (s3fifo *S3FIFOCache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	// read the time of this request
+	now := s3fifo.clock.Now()
+
+	// check if there is an item in the cache with the given key
+	existing_item, ok := s3fifo.keys_to_items[key]
+	if ok && !s3fifo.resident(existing_item) {
+		ok = false
+	}
+
+	// an expired item is removed and counts as a miss
+	if ok && expired(existing_item.Value.(*S3FIFOCacheItem[K, V]).expires_at, now) {
+		s3fifo.remove(existing_item)
+		ok = false
+	}
+
+	if !ok {
+		s3fifo.misses++
+		s3fifo.miss_bytes += requestSize(opts)
+		return value, false
+	}
+
+	s3fifo.hits++
+
+	item := existing_item.Value.(*S3FIFOCacheItem[K, V])
+	item.frequency = min(item.frequency+1, s3fifo_max_frequency)
+	s3fifo.hit_bytes += item.size
+
+	return item.value, true
+}
+
+// Set sets the value of the item with the given key,
+// possibly evicting items to make room for a new key insertion.
+// New items enter the small queue, unless their key is remembered in
+// the ghost queue, in which case they enter the main queue.
+// This operation counts as a "use" for an item already in the cache.
+// Returns true if the item was added/updated successfully, else false.
+func (s3fifo *S3FIFOCache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	// read the time of this request
+	operation_timestamp := s3fifo.clock.Now()
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
+
+	// can not set if cache max capacity is 0!
+	if s3fifo.max_capacity == 0 {
+		return false, nil
+	}
+
+	item_weight := weight(s3fifo.by_bytes, description.size)
+
+	// check if there is an existing item with the key
+	existing_item, ok := s3fifo.keys_to_items[key]
+
+	// an item larger than the whole cache can never fit,
+	// and any older value for its key is now stale
+	if item_weight > s3fifo.max_capacity {
+		if ok && s3fifo.resident(existing_item) {
+			s3fifo.remove(existing_item)
+		}
+		return false, nil
+	}
+
+	if ok && s3fifo.resident(existing_item) {
+		item := existing_item.Value.(*S3FIFOCacheItem[K, V])
+		item.value = value
+		item.expires_at = expiry(operation_timestamp, description.ttl)
+		item.frequency = min(item.frequency+1, s3fifo_max_frequency)
+
+		// account for a change in size, evicting other
+		// items if the item no longer fits
+		item.location.used += item_weight - weight(s3fifo.by_bytes, item.size)
+		item.size = description.size
+		s3fifo.makeRoom(0, existing_item)
+
+		return true, nil
+	}
+
+	// a key remembered in the ghost queue was evicted too
+	// early, so it goes straight to the main queue
+	location := &s3fifo.small
+	if ok {
+		s3fifo.remove(existing_item)
+		location = &s3fifo.main
+	}
+
+	// check if we need to evict
+	s3fifo.makeRoom(item_weight, nil)
+
+	// insert the item into its queue and store the mapping
+	item := &S3FIFOCacheItem[K, V]{key: key, value: value, size: description.size,
+		expires_at: expiry(operation_timestamp, description.ttl), location: location}
+	s3fifo.keys_to_items[key] = location.linked_list.PushBack(item)
+	location.used += item_weight
+
+	return true, nil
+}
+
+// makeRoom evicts items until an item of the given weight fits in the
+// S3FIFOCache, from the small queue while it uses at least its share of
+// max_capacity and from the main queue otherwise. The protected item is
+// never evicted.
+func (s3fifo *S3FIFOCache[K, V]) makeRoom(item_weight int, protected *list.Element) {
+
+	for s3fifo.small.used+s3fifo.main.used+item_weight > s3fifo.max_capacity {
+
+		evicted := false
+		if s3fifo.small.used >= s3fifo.small_capacity {
+			evicted = s3fifo.evictSmall(protected)
+		}
+
+		// either queue may have nothing but the protected item, or
+		// the small queue may have moved all of its items to main
+		if !evicted {
+			evicted = s3fifo.evictMain(protected) || s3fifo.evictSmall(protected)
+		}
+
+		if !evicted {
+			return
+		}
+	}
+}
+
+// evictSmall moves the oldest items of the small queue that were used
+// to the main queue, until it finds one that was not used, which it
+// evicts to the ghost queue. Returns false if no item was evicted.
+func (s3fifo *S3FIFOCache[K, V]) evictSmall(protected *list.Element) bool {
+
+	for {
+		oldest := leastRecent(&s3fifo.small, protected)
+		if oldest == nil {
+			return false
+		}
+
+		if oldest.Value.(*S3FIFOCacheItem[K, V]).frequency > 0 {
+			s3fifo.move(oldest, &s3fifo.main)
+			continue
+		}
+
+		s3fifo.move(oldest, &s3fifo.ghost)
+		s3fifo.evictions++
+
+		// the ghost queue remembers as much as the main queue holds
+		for s3fifo.ghost.used > s3fifo.max_capacity-s3fifo.small_capacity && s3fifo.ghost.used > 0 {
+			s3fifo.remove(s3fifo.ghost.linked_list.Front())
+		}
+
+		return true
+	}
+}
+
+// evictMain reinserts the oldest items of the main queue that were used
+// since they were last reinserted, counting down their frequency, until
+// it finds one that was not used, which it evicts. Returns false if no
+// item was evicted.
+func (s3fifo *S3FIFOCache[K, V]) evictMain(protected *list.Element) bool {
+
+	for {
+		oldest := leastRecent(&s3fifo.main, protected)
+		if oldest == nil {
+			return false
+		}
+
+		item := oldest.Value.(*S3FIFOCacheItem[K, V])
+		if item.frequency > 0 {
+			item.frequency--
+			s3fifo.main.linked_list.MoveToBack(oldest)
+			continue
+		}
+
+		s3fifo.remove(oldest)
+		s3fifo.evictions++
+
+		return true
+	}
+}
+
+// Delete removes the item with the given key from the S3FIFOCache.
+// Returns true if the item was found and removed, else false.
+func (s3fifo *S3FIFOCache[K, V]) Delete(key K) (success bool) {
+
+	// check if there is an item in the cache with the given key
+	existing_item, ok := s3fifo.keys_to_items[key]
+	if !ok || !s3fifo.resident(existing_item) {
+		return false
+	}
+
+	s3fifo.remove(existing_item)
+
+	return true
+}
+
+// resident returns true if an item is in the small or main queue,
+// rather than remembered in the ghost queue.
+func (s3fifo *S3FIFOCache[K, V]) resident(element *list.Element) bool {
+	return element.Value.(*S3FIFOCacheItem[K, V]).location != &s3fifo.ghost
+}
+
+// move moves an item to the newest end of the given queue and returns
+// its new element. Items moved to the ghost queue drop their values.
+func (s3fifo *S3FIFOCache[K, V]) move(element *list.Element, location *segment) *list.Element {
+
+	item := element.Value.(*S3FIFOCacheItem[K, V])
+	item_weight := weight(s3fifo.by_bytes, item.size)
+
+	item.location.linked_list.Remove(element)
+	item.location.used -= item_weight
+
+	if location == &s3fifo.ghost {
+		var zero V
+		item.value = zero
+	}
+
+	item.location = location
+	location.used += item_weight
+
+	moved := location.linked_list.PushBack(item)
+	s3fifo.keys_to_items[item.key] = moved
+
+	return moved
+}
+
+// remove unlinks an item from its queue and the map.
+func (s3fifo *S3FIFOCache[K, V]) remove(element *list.Element) {
+
+	item := element.Value.(*S3FIFOCacheItem[K, V])
+
+	item.location.linked_list.Remove(element)
+	item.location.used -= weight(s3fifo.by_bytes, item.size)
+	delete(s3fifo.keys_to_items, item.key)
+}
+
+// Contains returns true if an item with the key is in the small or main
+// queue of the S3FIFOCache, whether or not it has expired, without
+// counting as a use.
+func (s3fifo *S3FIFOCache[K, V]) Contains(key K) bool {
+	existing_item, ok := s3fifo.keys_to_items[key]
+	return ok && s3fifo.resident(existing_item)
+}
+
+// Victim returns the key of the item the S3FIFOCache would evict first
+// to make room for a new item of the given size, and true, or false if
+// the item fits without evicting anything. Only the oldest items of each
+// queue are looked at, so the victim is an estimate when all of them
+// were used.
+func (s3fifo *S3FIFOCache[K, V]) Victim(size int) (key K, ok bool) {
+
+	if s3fifo.small.used+s3fifo.main.used+weight(s3fifo.by_bytes, size) <= s3fifo.max_capacity {
+		return key, false
+	}
+
+	// the oldest item of the small queue that was not used, unless
+	// the main queue is evicted from or every item would move to it
+	if s3fifo.small.used >= s3fifo.small_capacity || s3fifo.main.linked_list.Len() == 0 {
+		victim := s3fifo.leastUsed(&s3fifo.small)
+		if victim != nil && (victim.frequency == 0 || s3fifo.main.linked_list.Len() == 0) {
+			return victim.key, true
+		}
+	}
+
+	// the oldest item of the main queue with the lowest frequency,
+	// which the reinsertions of evictMain come around to first
+	victim := s3fifo.leastUsed(&s3fifo.main)
+	if victim == nil {
+		return key, false
+	}
+
+	return victim.key, true
+}
+
+// leastUsed returns the oldest item with the lowest frequency out of the
+// oldest s3fifo_victim_scan items of the queue, or nil if it is empty.
+func (s3fifo *S3FIFOCache[K, V]) leastUsed(queue *segment) *S3FIFOCacheItem[K, V] {
+
+	var victim *S3FIFOCacheItem[K, V]
+
+	element := queue.linked_list.Front()
+	for scanned := 0; element != nil && scanned < s3fifo_victim_scan; scanned++ {
+		if item := element.Value.(*S3FIFOCacheItem[K, V]); victim == nil || item.frequency < victim.frequency {
+			victim = item
+		}
+
+		// no item is used less than one that was not used
+		if victim.frequency == 0 {
+			break
+		}
+
+		element = element.Next()
+	}
+
+	return victim
+}
+
+// Stats returns statistics about how many search hits and misses have
+// occurred in the S3FIFOCache.
+func (s3fifo *S3FIFOCache[K, V]) Stats() *Stats {
+	return &Stats{Hits: s3fifo.hits, Misses: s3fifo.misses,
+		HitBytes: s3fifo.hit_bytes, MissBytes: s3fifo.miss_bytes, Evictions: s3fifo.evictions}
+}
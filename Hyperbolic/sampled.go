@@ -0,0 +1,592 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// ItemMetadata is what a SampledCache knows about an item
+// when it decides which item to evict.
+type ItemMetadata struct {
+
+	// how many times the item has been accessed
+	AccessCount int
+
+	// when the item was first inserted
+	InsertTime int
+
+	// when the item was last accessed
+	LastAccessTime int
+
+	// size of the item, given with WithSize, whether or not
+	// the max capacity counts bytes
+	Size int
+
+	// cost of fetching the item again after a miss
+	Cost float64
+
+	// priority of the most recently evicted item when the item was last
+	// accessed, for policies that age items by inflating new priorities
+	Inflation float64
+}
+
+// A PriorityFunc computes the priority of an item at the given time.
+// A SampledCache evicts the sampled item with the lowest priority.
+type PriorityFunc func(item ItemMetadata, now int) (priority float64)
+
+// HyperbolicPriority is the priority of the hyperbolic caching algorithm:
+// cost * number of accesses / (size * time in cache), which is number of
+// accesses / time in cache for items with the default cost and size of 1.
+// An item inserted at the current time counts as having been in the
+// cache for 1 tick.
+func HyperbolicPriority(item ItemMetadata, now int) (priority float64) {
+
+	// calculate the time since item's
+	// initial insertion into the cache
+	time_in_cache := max(now-item.InsertTime, 1)
+
+	// multiply as floats, since a large size times a time in
+	// nanoseconds can overflow an int
+	return item.Cost * float64(item.AccessCount) / (float64(item.Size) * float64(time_in_cache))
+}
+
+// LRUPriority approximates least-recently-used eviction
+// by evicting the item that was accessed the longest ago.
+func LRUPriority(item ItemMetadata, now int) (priority float64) {
+	return float64(item.LastAccessTime)
+}
+
+// LFUPriority approximates least-frequently-used eviction
+// by evicting the item with the fewest accesses.
+func LFUPriority(item ItemMetadata, now int) (priority float64) {
+	return float64(item.AccessCount)
+}
+
+// GDSFPriority is the priority of Greedy-Dual-Size-Frequency:
+// inflation + number of accesses * cost / size. Inflating the priority by
+// that of the last evicted item lets items that stop being accessed age out.
+func GDSFPriority(item ItemMetadata, now int) (priority float64) {
+	return item.Inflation + float64(item.AccessCount)*item.Cost/float64(item.Size)
+}
+
+// A SampledCacheItem is an item with metadata that
+// holds a value. It goes in a SampledCache.
+type SampledCacheItem[K comparable, V any] struct {
+
+	// the item's key
+	key K
+
+	// the item's value
+	value V
+
+	// position of the item in the cache's slice of items
+	index int
+
+	// whether the item is a retained eviction candidate
+	retained bool
+
+	// when the item expires, or 0 if it never expires
+	expires_at int
+
+	// what the cache's priority function knows about the item
+	metadata ItemMetadata
+}
+
+// A SampledCache is a cache that evicts the item with the lowest
+// priority out of a random sample of its items.
+type SampledCache[K comparable, V any] struct {
+
+	// maximum number of items (or bytes, in byte
+	// capacity mode) the cache can hold
+	max_capacity int
+
+	// whether max_capacity counts bytes instead of items
+	by_bytes bool
+
+	// total number of items currently in the cache
+	size int
+
+	// how much of max_capacity the items currently in the cache use
+	used int
+
+	// map of keys to items in the cache
+	keys_to_items map[K]*SampledCacheItem[K, V]
+
+	// dense slice of the items in the cache, so that
+	// they can be sampled by index
+	items []*SampledCacheItem[K, V]
+
+	// sample size for eviction
+	sample_size int
+
+	// priority of items for eviction
+	priority PriorityFunc
+
+	// source of randomness for sampling
+	random *rand.Rand
+
+	// number of eviction candidates to carry over between evictions
+	retained_candidates int
+
+	// eviction candidates carried over from the last eviction
+	retained []*SampledCacheItem[K, V]
+
+	// eviction picked by Victim to be made next, unless its
+	// item is used or removed first
+	victim *evictionChoice[K, V]
+
+	// time of the most recent Get or Set
+	last_request int
+
+	// clock that tells the cache the time of accesses,
+	// evictions and expirations
+	clock Clock
+
+	// priority of the most recently evicted item
+	inflation float64
+
+	// number of hits
+	hits int
+
+	// number of misses
+	misses int
+
+	// total size of the items hit
+	hit_bytes int
+
+	// total size of the items missed
+	miss_bytes int
+
+	// number of items evicted
+	evictions int
+}
+
+// NewSampledCache creates a new, empty SampledCache that evicts items
+// by the given priority. The sample size must be positive (unless the
+// max capacity is 0) and can not be greater than the max capacity
+// (unless it counts bytes).
+func NewSampledCache[K comparable, V any](max_capacity int, sample_size int, priority PriorityFunc, opts ...Option) (*SampledCache[K, V], error) {
+
+	if err := validateCapacity(max_capacity); err != nil {
+		return nil, err
+	}
+
+	if sample_size < 0 {
+		return nil, fmt.Errorf("%w %d: %w", ErrInvalidSampleSize, sample_size, ErrNegativeValue)
+	}
+
+	config := newOptions(opts)
+
+	// the sampling size can not be greater than
+	// the number of items this cache can hold!
+	if sample_size > max_capacity && !config.by_bytes {
+		return nil, fmt.Errorf("%w %d: greater than max capacity %d",
+			ErrInvalidSampleSize, sample_size, max_capacity)
+	}
+
+	// a cache that can hold items needs something to sample when evicting
+	if sample_size == 0 && max_capacity > 0 {
+		return nil, fmt.Errorf("%w %d: must be positive", ErrInvalidSampleSize, sample_size)
+	}
+
+	if priority == nil {
+		return nil, ErrInvalidPriority
+	}
+
+	if config.retained_candidates < 0 {
+		return nil, fmt.Errorf("%w: %d retained candidates: %w",
+			ErrInvalidSampleSize, config.retained_candidates, ErrNegativeValue)
+	}
+
+	return &SampledCache[K, V]{
+		max_capacity:  max_capacity,
+		by_bytes:      config.by_bytes,
+		clock:         config.clock,
+		size:          0,
+		used:          0,
+		keys_to_items: make(map[K]*SampledCacheItem[K, V]),
+		items:         make([]*SampledCacheItem[K, V], 0),
+		sample_size:   sample_size,
+		priority:      priority,
+		random:        rand.New(config.source),
+		hits:          0,
+		misses:        0,
+
+		retained_candidates: config.retained_candidates,
+	}, nil
+}
+
+// Get returns the value of the item with the key and a success
+// boolean indicating if the item was found.
+func (cache *SampledCache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	// read the time of this request
+	now := cache.clock.Now()
+	cache.last_request = now
+
+	// retrieve item associated with key
+	item, ok := cache.keys_to_items[key]
+
+	// an expired item is removed and counts as a miss
+	if ok && expired(item.expires_at, now) {
+		cache.Delete(key)
+		ok = false
+	}
+
+	if ok {
+		cache.hits += 1
+		cache.hit_bytes += item.metadata.Size
+
+		// update access metadata of item
+		cache.access(item, now)
+
+	} else {
+		cache.misses += 1
+		cache.miss_bytes += requestSize(opts)
+
+		return value, false
+	}
+
+	return item.value, true
+}
+
+// Set adds/updates an item with the given key and value in the cache
+// and returns a success boolean. The item's cost and size, given with
+// WithCost and WithSize, are passed on to the priority function, and
+// setting an existing key replaces them. An error is returned if an
+// option is invalid or if an item could not be evicted to make room
+// for the new one.
+func (cache *SampledCache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	// read the time of this request
+	operation_timestamp := cache.clock.Now()
+	cache.last_request = operation_timestamp
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
+
+	// can not set if cache max capacity is 0!
+	if cache.max_capacity == 0 {
+		return false, nil
+	}
+
+	item_weight := weight(cache.by_bytes, description.size)
+
+	// check if an item with that key already exists
+	existing_item, ok := cache.keys_to_items[key]
+
+	// an item larger than the whole cache can never fit,
+	// and any older value for its key is now stale
+	if item_weight > cache.max_capacity {
+		if ok {
+			cache.Delete(key)
+		}
+		return false, nil
+	}
+
+	if ok {
+		existing_item.value = value
+		existing_item.expires_at = expiry(operation_timestamp, description.ttl)
+		existing_item.metadata.Cost = description.cost
+
+		// update access metadata of item
+		cache.access(existing_item, operation_timestamp)
+
+		// account for a change in size, evicting other
+		// items if the item no longer fits
+		cache.used += item_weight - weight(cache.by_bytes, existing_item.metadata.Size)
+		existing_item.metadata.Size = description.size
+		if err := cache.makeRoom(operation_timestamp, 0, existing_item); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	// if not enough space and an item with the key does not exist,
+	// evict items
+	if err := cache.makeRoom(operation_timestamp, item_weight, nil); err != nil {
+		return false, err
+	}
+
+	// add new item with key to the end of the slice of items
+	new_item := &SampledCacheItem[K, V]{
+		key:        key,
+		value:      value,
+		index:      len(cache.items),
+		expires_at: expiry(operation_timestamp, description.ttl),
+		metadata: ItemMetadata{
+			AccessCount:    1,
+			InsertTime:     operation_timestamp,
+			LastAccessTime: operation_timestamp,
+			Size:           description.size,
+			Cost:           description.cost,
+			Inflation:      cache.inflation}}
+
+	cache.keys_to_items[key] = new_item
+	cache.items = append(cache.items, new_item)
+
+	// update size of cache
+	cache.size += 1
+	cache.used += item_weight
+
+	return true, nil
+}
+
+// makeRoom evicts items until an item of the given weight fits in the
+// cache. The protected item is never evicted.
+func (cache *SampledCache[K, V]) makeRoom(eviction_timestamp int, item_weight int, protected *SampledCacheItem[K, V]) error {
+
+	for cache.used+item_weight > cache.max_capacity {
+
+		// evict the item picked by Victim if it is still in the cache
+		var key_to_remove K
+		var err error
+		if cache.pinned() && cache.victim.item != protected {
+			cache.commit(cache.victim)
+			key_to_remove = cache.victim.item.key
+		} else {
+			key_to_remove, err = cache.evict_Which(eviction_timestamp, protected)
+		}
+		cache.victim = nil
+
+		if err != nil {
+			return err
+		}
+
+		success := cache.Delete(key_to_remove)
+		if !success {
+			return fmt.Errorf("%w: failed to evict an item", ErrInconsistentState)
+		}
+		cache.evictions++
+	}
+
+	return nil
+}
+
+// access updates the metadata of an item that was accessed at the given time.
+func (cache *SampledCache[K, V]) access(item *SampledCacheItem[K, V], access_timestamp int) {
+
+	// a used item is no longer a good victim
+	if cache.victim != nil && item == cache.victim.item {
+		cache.victim = nil
+	}
+
+	item.metadata.AccessCount += 1
+	item.metadata.LastAccessTime = access_timestamp
+	item.metadata.Inflation = cache.inflation
+}
+
+// An evictionChoice is an item picked to be evicted, with what
+// evicting it changes in the cache.
+type evictionChoice[K comparable, V any] struct {
+
+	// the item to evict
+	item *SampledCacheItem[K, V]
+
+	// priority of the item when it was picked, which becomes
+	// the cache's inflation
+	priority float64
+
+	// eviction candidates to carry over to the next eviction
+	retained []*SampledCacheItem[K, V]
+}
+
+// evict_Which() is an algorithm to select which item in the cache, other
+// than the protected item, to evict. An error is returned if the cache
+// is not in a state to evict from.
+func (cache *SampledCache[K, V]) evict_Which(eviction_timestamp int, protected *SampledCacheItem[K, V]) (key K, err error) {
+
+	choice, err := cache.choose(eviction_timestamp, protected)
+	if err != nil {
+		return key, err
+	}
+
+	cache.commit(choice)
+
+	return choice.item.key, nil
+}
+
+// choose samples the items of the cache, other than the protected item,
+// and picks the one with the lowest priority. It does not change the
+// retained candidates or the inflation of the cache, so that a choice
+// can be made ahead of the eviction and committed only if it happens.
+func (cache *SampledCache[K, V]) choose(eviction_timestamp int, protected *SampledCacheItem[K, V]) (*evictionChoice[K, V], error) {
+
+	// make sure every item can be sampled
+	if len(cache.items) != cache.size {
+		return nil, fmt.Errorf("%w: cache of size %d only holds %d items",
+			ErrInconsistentState, cache.size, len(cache.items))
+	}
+
+	// keep the protected item out of the sample by moving it to the end
+	evictable := cache.size
+	if protected != nil {
+		cache.swap(protected.index, evictable-1)
+		evictable--
+	}
+
+	// make sure there is something to evict
+	if evictable == 0 {
+		return nil, fmt.Errorf("%w: no items to evict", ErrInconsistentState)
+	}
+
+	// a cache with a byte capacity may hold fewer items than the sample size
+	sample_size := min(cache.sample_size, evictable)
+
+	candidates := make([]*SampledCacheItem[K, V], 0, sample_size+len(cache.retained))
+
+	// start with the candidates retained from the last eviction
+	// that have not since been evicted or deleted
+	for _, candidate := range cache.retained {
+		if cache.keys_to_items[candidate.key] == candidate && candidate != protected {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	// draw the sample with a partial Fisher-Yates shuffle: the i-th
+	// candidate is swapped into position i from the unsampled items
+	// after it, so every item is equally likely to be sampled and no
+	// item is sampled twice
+	for i := 0; i < sample_size; i++ {
+		cache.swap(i, i+cache.random.Intn(evictable-i))
+
+		// retained candidates are already in the sample
+		if !cache.items[i].retained {
+			candidates = append(candidates, cache.items[i])
+		}
+	}
+
+	// find the candidate with the minimum priority
+	if cache.retained_candidates == 0 {
+		minimum := candidates[0]
+		minValue := cache.priority(minimum.metadata, eviction_timestamp)
+
+		for _, candidate := range candidates {
+			if p := cache.priority(candidate.metadata, eviction_timestamp); p < minValue {
+				minValue = p
+				minimum = candidate
+			}
+		}
+
+		return &evictionChoice[K, V]{item: minimum, priority: minValue}, nil
+	}
+
+	// order the candidates by priority, evict the minimum and
+	// retain the next lowest ones for the next eviction
+	priorities := make(map[*SampledCacheItem[K, V]]float64, len(candidates))
+	for _, candidate := range candidates {
+		priorities[candidate] = cache.priority(candidate.metadata, eviction_timestamp)
+	}
+
+	sort.SliceStable(candidates, func(i int, j int) bool {
+		return priorities[candidates[i]] < priorities[candidates[j]]
+	})
+
+	retained := candidates[1:min(len(candidates), cache.retained_candidates+1)]
+
+	return &evictionChoice[K, V]{item: candidates[0], priority: priorities[candidates[0]],
+		retained: retained}, nil
+}
+
+// commit makes the changes of evicting the chosen item: its priority
+// becomes the cache's inflation, and its candidates are retained.
+func (cache *SampledCache[K, V]) commit(choice *evictionChoice[K, V]) {
+
+	for _, candidate := range cache.retained {
+		candidate.retained = false
+	}
+
+	cache.retained = append(cache.retained[:0], choice.retained...)
+	for _, candidate := range cache.retained {
+		candidate.retained = true
+	}
+
+	cache.inflation = choice.priority
+}
+
+// swap swaps the items at positions i and j of the cache's slice of items.
+func (cache *SampledCache[K, V]) swap(i int, j int) {
+	cache.items[i], cache.items[j] = cache.items[j], cache.items[i]
+	cache.items[i].index = i
+	cache.items[j].index = j
+}
+
+// Delete removes the item associated with the given key from the cache, if it exists.
+// ok is true if an item was found and false otherwise.
+func (cache *SampledCache[K, V]) Delete(key K) (ok bool) {
+
+	// check if there is an item associated with key
+	item, ok := cache.keys_to_items[key]
+	if !ok {
+		return false
+	}
+
+	// remove the key from the cache
+	delete(cache.keys_to_items, key)
+
+	// move the last item into the removed item's place in the slice
+	last := len(cache.items) - 1
+	cache.swap(item.index, last)
+	cache.items[last] = nil
+	cache.items = cache.items[:last]
+
+	cache.size -= 1
+	cache.used -= weight(cache.by_bytes, item.metadata.Size)
+
+	return true
+}
+
+// Contains returns true if an item with the key is in the cache,
+// whether or not it has expired, without counting as a use.
+func (cache *SampledCache[K, V]) Contains(key K) bool {
+	_, ok := cache.keys_to_items[key]
+	return ok
+}
+
+// Victim returns the key of the item the cache would evict first to
+// make room for a new item of the given size, and true, or false if
+// the item fits without evicting anything. The victim is sampled at the
+// time of the cache's most recent request, and is the next item evicted
+// unless it is used or removed first, or an AdmissionCache rejects the
+// item it was picked for. Until then, the retained
+// candidates and inflation of the cache are left as they are.
+func (cache *SampledCache[K, V]) Victim(size int) (key K, ok bool) {
+
+	if cache.used+weight(cache.by_bytes, size) <= cache.max_capacity || cache.size == 0 {
+		return key, false
+	}
+
+	if cache.pinned() {
+		return cache.victim.item.key, true
+	}
+
+	choice, err := cache.choose(cache.last_request, nil)
+	if err != nil {
+		return key, false
+	}
+
+	cache.victim = choice
+
+	return choice.item.key, true
+}
+
+// unpinVictim forgets the item picked by Victim, so that the next
+// eviction samples a victim of its own.
+func (cache *SampledCache[K, V]) unpinVictim() {
+	cache.victim = nil
+}
+
+// pinned returns true if the item picked by Victim is still in the cache.
+func (cache *SampledCache[K, V]) pinned() bool {
+	return cache.victim != nil && cache.keys_to_items[cache.victim.item.key] == cache.victim.item
+}
+
+// Stats returns statistics about how many search hits and misses have occurred.
+func (cache *SampledCache[K, V]) Stats() *Stats {
+	return &Stats{Hits: cache.hits, Misses: cache.misses,
+		HitBytes: cache.hit_bytes, MissBytes: cache.miss_bytes, Evictions: cache.evictions}
+}
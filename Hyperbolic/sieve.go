@@ -0,0 +1,323 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// largest number of items a SIEVECache's hand looks at to find its
+// victim without evicting it, so that finding it does not take longer
+// with more items
+const sieve_victim_scan = 16
+
+// A SIEVECache is a fixed-size, in-memory cache with SIEVE eviction. It
+// keeps its items in a single FIFO queue, like a FIFOCache, and marks
+// items as visited when they are hit. A hand walks the queue from the
+// oldest item towards the newest, clearing the visited bit of the items
+// it passes and evicting the first item that was not visited. Items are
+// never moved, so hits are as cheap as in a FIFOCache.
+type SIEVECache[K comparable, V any] struct {
+
+	// total number of items (or bytes, in byte capacity
+	// mode) the SIEVECache can store
+	max_capacity int
+
+	// whether max_capacity counts bytes instead of items
+	by_bytes bool
+
+	// total number of items currently in the SIEVECache
+	size int
+
+	// how much of max_capacity the items currently in the SIEVECache use
+	used int
+
+	// mapping of keys to items in the SIEVECache
+	keys_to_items map[K]*list.Element
+
+	// linked list of items in the SIEVECache, oldest first
+	linked_list *list.List
+
+	// next item the hand looks at, or nil to start from the oldest item
+	hand *list.Element
+
+	// number of hits from the SIEVECache
+	hits int
+
+	// number of misses from the SIEVECache
+	misses int
+
+	// total size of the items hit in the SIEVECache
+	hit_bytes int
+
+	// total size of the items missed in the SIEVECache
+	miss_bytes int
+
+	// number of items evicted from the SIEVECache
+	evictions int
+
+	// clock that tells the SIEVECache when items expire
+	clock Clock
+}
+
+// A SIEVECacheItem holds a key, value pair to be put in a linked list.
+type SIEVECacheItem[K comparable, V any] struct {
+	key        K
+	value      V
+	size       int
+	expires_at int
+
+	// whether the item was used since the hand last passed it
+	visited bool
+}
+
+// NewSIEVECache returns a pointer to a new, empty SIEVECache.
+func NewSIEVECache[K comparable, V any](max_capacity int, opts ...Option) (*SIEVECache[K, V], error) {
+
+	if err := validateCapacity(max_capacity); err != nil {
+		return nil, err
+	}
+
+	config := newOptions(opts)
+
+	// create and initialize a new SIEVECache
+	return &SIEVECache[K, V]{
+		max_capacity:  max_capacity,
+		by_bytes:      config.by_bytes,
+		clock:         config.clock,
+		size:          0,
+		used:          0,
+		keys_to_items: make(map[K]*list.Element),
+		linked_list:   list.New(),
+		hand:          nil,
+		hits:          0,
+		misses:        0,
+	}, nil
+}
+
+// Get returns the value of the item with the key and a success boolean
+// indicating if the item was found in the cache.
+// This operation marks the item as visited.
+func (sieve *SIEVECache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	// read the time of this request
+	now := sieve.clock.Now()
+
+	// check if there is an item with the given key
+	existing_item, ok := sieve.keys_to_items[key]
+
+	// an expired item is removed and counts as a miss
+	if ok && expired(existing_item.Value.(*SIEVECacheItem[K, V]).expires_at, now) {
+		sieve.remove(existing_item)
+		ok = false
+	}
+
+	if !ok {
+		sieve.misses++
+		sieve.miss_bytes += requestSize(opts)
+		return value, false
+	}
+
+	sieve.hits++
+
+	item := existing_item.Value.(*SIEVECacheItem[K, V])
+	item.visited = true
+	sieve.hit_bytes += item.size
+
+	return item.value, true
+}
+
+// Set sets the value of the item with the given key,
+// possibly evicting items to make room for a new key insertion.
+// This operation marks an item that is already in the cache as visited.
+// Returns true if the item was added/updated successfully, else false.
+func (sieve *SIEVECache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	// read the time of this request
+	operation_timestamp := sieve.clock.Now()
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
+
+	// can not set if cache max capacity is 0!
+	if sieve.max_capacity == 0 {
+		return false, nil
+	}
+
+	item_weight := weight(sieve.by_bytes, description.size)
+
+	// check if there is an existing item with the key
+	existing_item, ok := sieve.keys_to_items[key]
+
+	// an item larger than the whole cache can never fit,
+	// and any older value for its key is now stale
+	if item_weight > sieve.max_capacity {
+		if ok {
+			sieve.remove(existing_item)
+		}
+		return false, nil
+	}
+
+	if ok {
+		item := existing_item.Value.(*SIEVECacheItem[K, V])
+		item.value = value
+		item.expires_at = expiry(operation_timestamp, description.ttl)
+		item.visited = true
+
+		// account for a change in size, evicting other
+		// items if the item no longer fits
+		sieve.used += item_weight - weight(sieve.by_bytes, item.size)
+		item.size = description.size
+		sieve.makeRoom(0, existing_item)
+
+		return true, nil
+	}
+
+	// item with the key does not exist, so check if we need to evict
+	sieve.makeRoom(item_weight, nil)
+
+	// insert the item at the newest end of the linked list
+	entry := sieve.linked_list.PushBack(&SIEVECacheItem[K, V]{key: key, value: value,
+		size: description.size, expires_at: expiry(operation_timestamp, description.ttl)})
+	sieve.keys_to_items[key] = entry
+
+	// update the size of the SIEVECache
+	sieve.size++
+	sieve.used += item_weight
+
+	return true, nil
+}
+
+// makeRoom moves the hand and evicts items until an item of the given
+// weight fits in the SIEVECache. The protected item is never evicted.
+func (sieve *SIEVECache[K, V]) makeRoom(item_weight int, protected *list.Element) {
+
+	for sieve.used+item_weight > sieve.max_capacity {
+
+		victim := sieve.sweep(protected, true)
+		if victim == nil {
+			return
+		}
+
+		// the hand continues from the next newer item
+		sieve.hand = victim.Next()
+
+		sieve.remove(victim)
+		sieve.evictions++
+	}
+}
+
+// sweep walks the hand from its position towards the newest item,
+// wrapping around to the oldest, and returns the first item other than
+// the protected item that was not visited. If clear_visited is true, the
+// visited bits of the items the hand passes are cleared; otherwise the
+// walk only looks, at no more than sieve_victim_scan items. Returns nil
+// if there is no item but the protected one.
+func (sieve *SIEVECache[K, V]) sweep(protected *list.Element, clear_visited bool) *list.Element {
+
+	if sieve.linked_list.Len() == 0 {
+		return nil
+	}
+
+	element := sieve.hand
+
+	// a walk that only looks evicts the first item it passes if
+	// every item it looks at was visited, since a walk that
+	// clears would come back around to it
+	var first *list.Element
+
+	// every item is passed at most twice, once to clear it
+	// and once to evict it
+	limit := 2 * sieve.linked_list.Len()
+	if !clear_visited {
+		limit = min(limit, sieve_victim_scan)
+	}
+
+	for steps := 0; steps <= limit; steps++ {
+
+		if element == nil {
+			element = sieve.linked_list.Front()
+		}
+
+		if element != protected {
+			item := element.Value.(*SIEVECacheItem[K, V])
+			if !item.visited {
+				return element
+			}
+
+			if clear_visited {
+				item.visited = false
+			} else if first == nil {
+				first = element
+			}
+		}
+
+		element = element.Next()
+	}
+
+	return first
+}
+
+// Delete removes the item with the given key from the SIEVECache.
+// Returns true if the item was found and removed, else false.
+func (sieve *SIEVECache[K, V]) Delete(key K) (success bool) {
+
+	// check if there is an item with the given key
+	existing_item, ok := sieve.keys_to_items[key]
+	if !ok {
+		return false
+	}
+
+	sieve.remove(existing_item)
+
+	return true
+}
+
+// remove unlinks an item from the linked list and the map, moving the
+// hand on to the next newer item if it points at the item.
+func (sieve *SIEVECache[K, V]) remove(element *list.Element) {
+
+	if sieve.hand == element {
+		sieve.hand = element.Next()
+	}
+
+	item := sieve.linked_list.Remove(element).(*SIEVECacheItem[K, V])
+	delete(sieve.keys_to_items, item.key)
+
+	// update the size of the SIEVECache
+	sieve.size--
+	sieve.used -= weight(sieve.by_bytes, item.size)
+}
+
+// Contains returns true if an item with the key is in the SIEVECache,
+// whether or not it has expired, without counting as a use.
+func (sieve *SIEVECache[K, V]) Contains(key K) bool {
+	_, ok := sieve.keys_to_items[key]
+	return ok
+}
+
+// Victim returns the key of the item the SIEVECache would evict first to
+// make room for a new item of the given size, and true, or false if
+// the item fits without evicting anything. Only the items next to the
+// hand are looked at, so the victim is an estimate when all of them
+// were visited.
+func (sieve *SIEVECache[K, V]) Victim(size int) (key K, ok bool) {
+
+	if sieve.used+weight(sieve.by_bytes, size) <= sieve.max_capacity {
+		return key, false
+	}
+
+	victim := sieve.sweep(nil, false)
+	if victim == nil {
+		return key, false
+	}
+
+	return victim.Value.(*SIEVECacheItem[K, V]).key, true
+}
+
+// Stats returns statistics about how many search hits and misses have
+// occurred in the SIEVECache.
+func (sieve *SIEVECache[K, V]) Stats() *Stats {
+	return &Stats{Hits: sieve.hits, Misses: sieve.misses,
+		HitBytes: sieve.hit_bytes, MissBytes: sieve.miss_bytes, Evictions: sieve.evictions}
+}
@@ -0,0 +1,156 @@
+package sim
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	cache "github.com/jimmytienhoangy/COS316_Project"
+)
+
+// An Experiment is a single run of a caching policy on a Source.
+type Experiment struct {
+
+	// where the experiment's records come from
+	Source Source
+
+	// name of the caching policy, one of Policies
+	Policy string
+
+	// max capacity of the cache
+	Capacity int
+
+	// sample size of Sampled policies, capped at the max capacity,
+	// or 0 for other policies
+	SampleSize int
+
+	// sample size of Sampled policies as asked for, before it was
+	// capped, or 0 for other policies, which tells apart the runs
+	// of each asked for sample size across max capacities
+	RequestedSampleSize int
+
+	// seed of the randomness of Sampled policies, or 0 for other policies
+	Seed int64
+}
+
+// Run runs the Experiment on a new cache, seeded with the Experiment's
+// seed, and returns its Result.
+func (experiment Experiment) Run(opts ...cache.Option) Result {
+
+	start := time.Now()
+
+	stats, requests, err := runCacheExperiment(experiment.Source, experiment.Policy,
+		experiment.Capacity, experiment.SampleSize,
+		append(opts, cache.WithSource(rand.NewSource(experiment.Seed))))
+
+	return Result{Experiment: experiment, Stats: stats, Requests: requests,
+		WallTime: time.Since(start), Err: err}
+}
+
+// A Result is the outcome of an Experiment.
+type Result struct {
+	Experiment
+
+	// statistics of the experiment's cache, if it ran without error
+	Stats *cache.Stats
+
+	// number of records replayed
+	Requests int
+
+	// how long the experiment took to run
+	WallTime time.Duration
+
+	// why the experiment failed, or nil if it did not
+	Err error
+}
+
+// A Grid is every combination of its Sources, Policies, Capacities,
+// SampleSizes and Seeds. Sample sizes and seeds only change the results
+// of Sampled policies, so the other policies run once per capacity and
+// source.
+type Grid struct {
+	Sources     []Source
+	Policies    []string
+	Capacities  []int
+	SampleSizes []int
+	Seeds       []int64
+}
+
+// Experiments returns every Experiment in the Grid, ordered by capacity,
+// then source, then policy, then sample size, then seed. Experiments of
+// policies that are not Sampled have a sample size and seed of 0, and
+// sample sizes larger than the capacity are capped at the capacity, as
+// NewCache does. The sample size asked for is kept as the
+// RequestedSampleSize, so every sample size asked for runs at every
+// capacity, even if several of them are capped to the same size.
+func (grid Grid) Experiments() []Experiment {
+
+	experiments := []Experiment{}
+
+	for _, capacity := range grid.Capacities {
+		for _, source := range grid.Sources {
+			for _, policy := range grid.Policies {
+
+				if !Sampled(policy) {
+					experiments = append(experiments, Experiment{Source: source, Policy: policy,
+						Capacity: capacity})
+					continue
+				}
+
+				for _, sample_size := range grid.SampleSizes {
+					for _, seed := range grid.Seeds {
+						experiments = append(experiments, Experiment{Source: source, Policy: policy,
+							Capacity: capacity, SampleSize: min(sample_size, capacity),
+							RequestedSampleSize: sample_size, Seed: seed})
+					}
+				}
+			}
+		}
+	}
+
+	return experiments
+}
+
+// RunAll runs the Experiments on up to workers goroutines at a time, or
+// on one per CPU if workers is not positive. Every Experiment gets its
+// own cache and reader of its Source. The Results are in the same order
+// as the Experiments, however long each one took. An Experiment that
+// fails does not stop the others.
+func RunAll(experiments []Experiment, workers int, opts ...cache.Option) []Result {
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(experiments))
+
+	results := make([]Result, len(experiments))
+
+	// experiments append their own options, so make sure
+	// that they never append to the same array
+	opts = opts[:len(opts):len(opts)]
+
+	// hand out experiments by their index, so that each
+	// worker writes its results to their own place
+	indices := make(chan int)
+	var waiting sync.WaitGroup
+
+	for worker := 0; worker < workers; worker++ {
+		waiting.Add(1)
+		go func() {
+			defer waiting.Done()
+			for i := range indices {
+				results[i] = experiments[i].Run(opts...)
+			}
+		}()
+	}
+
+	for i := range experiments {
+		indices <- i
+	}
+	close(indices)
+
+	waiting.Wait()
+
+	return results
+}
@@ -0,0 +1,233 @@
+package sim
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+
+	cache "github.com/jimmytienhoangy/COS316_Project"
+)
+
+// A Curve is a ratio of a caching policy at each max capacity it ran with.
+type Curve struct {
+
+	// name of the curve in the legend
+	Name string
+
+	// max capacities, in increasing order
+	Capacities []int
+
+	// ratio at each max capacity
+	Ratios []float64
+}
+
+// HitRatio is the hit ratio of a cache's statistics.
+func HitRatio(stats *cache.Stats) float64 {
+	return stats.HitRatio()
+}
+
+// MissRatio is the miss ratio of a cache's statistics.
+func MissRatio(stats *cache.Stats) float64 {
+	return 1 - stats.HitRatio()
+}
+
+// Curves returns a Curve of ratio for every policy of the Results on the
+// source named source, in the order the policies first appear. Results
+// that failed are left out and the results of different seeds are
+// averaged. If the Sampled policies were asked to run with more than one
+// sample size, every sample size asked for has its own Curve, whatever
+// the sample size was capped to at each max capacity.
+func Curves(results []Result, source string, ratio func(*cache.Stats) float64) []Curve {
+
+	type point struct {
+		name     string
+		capacity int
+	}
+
+	names := []string{}
+	sums := map[point]float64{}
+	counts := map[point]int{}
+	sample_sizes := map[int]bool{}
+
+	for _, result := range results {
+		if result.Err == nil && result.Source.String() == source && Sampled(result.Policy) {
+			sample_sizes[result.RequestedSampleSize] = true
+		}
+	}
+
+	for _, result := range results {
+		if result.Err != nil || result.Source.String() != source {
+			continue
+		}
+
+		name := result.Policy
+		if len(sample_sizes) > 1 && Sampled(result.Policy) {
+			name = fmt.Sprintf("%s [sample size %d]", result.Policy, result.RequestedSampleSize)
+		}
+
+		key := point{name: name, capacity: result.Capacity}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+		sums[key] += ratio(result.Stats)
+		counts[key]++
+	}
+
+	curves := []Curve{}
+
+	for _, name := range names {
+		curve := Curve{Name: name}
+
+		for key := range counts {
+			if key.name == name {
+				curve.Capacities = append(curve.Capacities, key.capacity)
+			}
+		}
+		slices.Sort(curve.Capacities)
+
+		for _, capacity := range curve.Capacities {
+			key := point{name: name, capacity: capacity}
+			curve.Ratios = append(curve.Ratios, sums[key]/float64(counts[key]))
+		}
+
+		curves = append(curves, curve)
+	}
+
+	return curves
+}
+
+// dimensions of plots, in pixels
+const (
+	plot_width  = 720
+	plot_height = 420
+	plot_left   = 70
+	plot_right  = 540
+	plot_top    = 50
+	plot_bottom = 360
+)
+
+// colors of the curves, in order, repeating if there are more curves
+var plot_colors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+// WriteSVG writes the Curves as a standalone SVG line chart, with max
+// capacity on the x axis and ratios from 0 to 1 on the y axis. The x
+// axis is logarithmic if the max capacities span at least a factor of 10.
+func WriteSVG(output io.Writer, title string, y_label string, curves []Curve) error {
+
+	// find the range of max capacities on the x axis
+	capacities := []int{}
+	for _, curve := range curves {
+		for _, capacity := range curve.Capacities {
+			if !slices.Contains(capacities, capacity) {
+				capacities = append(capacities, capacity)
+			}
+		}
+	}
+	slices.Sort(capacities)
+
+	low, high := 0.0, 1.0
+	if len(capacities) > 0 {
+		low, high = float64(capacities[0]), float64(capacities[len(capacities)-1])
+	}
+
+	logarithmic := low > 0 && high/low >= 10
+	scale := func(capacity float64) float64 {
+		if logarithmic {
+			return math.Log10(capacity)
+		}
+		return capacity
+	}
+
+	// leave room around a single max capacity
+	low, high = scale(low), scale(high)
+	if low == high {
+		low, high = low-1, high+1
+	}
+
+	x := func(capacity int) float64 {
+		return plot_left + (scale(float64(capacity))-low)/(high-low)*(plot_right-plot_left)
+	}
+	y := func(ratio float64) float64 {
+		return plot_bottom - ratio*(plot_bottom-plot_top)
+	}
+
+	var svg strings.Builder
+
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" `+
+		`font-family="sans-serif" font-size="12">`+"\n", plot_width, plot_height, plot_width, plot_height)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="white"/>`+"\n", plot_width, plot_height)
+	fmt.Fprintf(&svg, `<text x="%d" y="%d" text-anchor="middle" font-size="16">%s</text>`+"\n",
+		(plot_left+plot_right)/2, plot_top/2, escape(title))
+
+	// draw the y axis, its grid lines and labels
+	for tick := 0; tick <= 5; tick++ {
+		ratio := float64(tick) / 5
+		fmt.Fprintf(&svg, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#dddddd"/>`+"\n",
+			plot_left, y(ratio), plot_right, y(ratio))
+		fmt.Fprintf(&svg, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%.1f</text>`+"\n",
+			plot_left-6, y(ratio), ratio)
+	}
+	fmt.Fprintf(&svg, `<text x="%d" y="%d" text-anchor="middle" transform="rotate(-90 %d %d)">%s</text>`+"\n",
+		plot_left-45, (plot_top+plot_bottom)/2, plot_left-45, (plot_top+plot_bottom)/2, escape(y_label))
+
+	// label the x axis at every max capacity, unless labels would overlap
+	last_label := math.Inf(-1)
+	for _, capacity := range capacities {
+		fmt.Fprintf(&svg, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="black"/>`+"\n",
+			x(capacity), plot_bottom, x(capacity), plot_bottom+5)
+		if x(capacity)-last_label >= 40 {
+			fmt.Fprintf(&svg, `<text x="%.1f" y="%d" text-anchor="middle">%d</text>`+"\n",
+				x(capacity), plot_bottom+20, capacity)
+			last_label = x(capacity)
+		}
+	}
+
+	x_label := "Max capacity"
+	if logarithmic {
+		x_label += " (log scale)"
+	}
+	fmt.Fprintf(&svg, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n",
+		(plot_left+plot_right)/2, plot_bottom+45, x_label)
+
+	fmt.Fprintf(&svg, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="black"/>`+"\n",
+		plot_left, plot_top, plot_right-plot_left, plot_bottom-plot_top)
+
+	// draw every curve, with a dot at each max capacity, and its legend entry
+	for i, curve := range curves {
+		color := plot_colors[i%len(plot_colors)]
+
+		points := []string{}
+		for j, capacity := range curve.Capacities {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(capacity), y(curve.Ratios[j])))
+		}
+
+		fmt.Fprintf(&svg, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n",
+			strings.Join(points, " "), color)
+		for j, capacity := range curve.Capacities {
+			fmt.Fprintf(&svg, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"/>`+"\n",
+				x(capacity), y(curve.Ratios[j]), color)
+		}
+
+		legend_y := plot_top + 10 + 20*i
+		fmt.Fprintf(&svg, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="2"/>`+"\n",
+			plot_right+15, legend_y, plot_right+35, legend_y, color)
+		fmt.Fprintf(&svg, `<text x="%d" y="%d" dominant-baseline="middle">%s</text>`+"\n",
+			plot_right+42, legend_y, escape(curve.Name))
+	}
+
+	svg.WriteString("</svg>\n")
+
+	_, err := io.WriteString(output, svg.String())
+	return err
+}
+
+// escape escapes text to be written inside an SVG element.
+func escape(text string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
@@ -0,0 +1,120 @@
+package sim
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// A Row is a Result flattened into the fields written by WriteCSV and
+// WriteJSONLines.
+type Row struct {
+	Trace               string  `json:"trace"`
+	Policy              string  `json:"policy"`
+	Capacity            int     `json:"capacity"`
+	SampleSize          int     `json:"sample_size"`
+	RequestedSampleSize int     `json:"requested_sample_size"`
+	Seed                int64   `json:"seed"`
+	Requests            int     `json:"requests"`
+	Hits                int     `json:"hits"`
+	Misses              int     `json:"misses"`
+	HitBytes            int     `json:"hit_bytes"`
+	MissBytes           int     `json:"miss_bytes"`
+	Evictions           int     `json:"evictions"`
+	HitRatio            float64 `json:"hit_ratio"`
+	ByteHitRatio        float64 `json:"byte_hit_ratio"`
+	WallSeconds         float64 `json:"wall_seconds"`
+
+	// why the experiment failed, or empty if it did not
+	Error string `json:"error,omitempty"`
+}
+
+// csv_header names the columns written by WriteCSV, in order.
+var csv_header = []string{"trace", "policy", "capacity", "sample_size", "requested_sample_size",
+	"seed", "requests", "hits", "misses", "hit_bytes", "miss_bytes", "evictions", "hit_ratio",
+	"byte_hit_ratio", "wall_seconds", "error"}
+
+// Row flattens the Result. A failed Result has no statistics.
+func (result Result) Row() Row {
+
+	row := Row{
+		Trace:               result.Source.String(),
+		Policy:              result.Policy,
+		Capacity:            result.Capacity,
+		SampleSize:          result.SampleSize,
+		RequestedSampleSize: result.RequestedSampleSize,
+		Seed:                result.Seed,
+		Requests:            result.Requests,
+		WallSeconds:         result.WallTime.Seconds(),
+	}
+
+	if result.Err != nil {
+		row.Error = result.Err.Error()
+	}
+
+	if result.Stats != nil {
+		row.Hits = result.Stats.Hits
+		row.Misses = result.Stats.Misses
+		row.HitBytes = result.Stats.HitBytes
+		row.MissBytes = result.Stats.MissBytes
+		row.Evictions = result.Stats.Evictions
+		row.HitRatio = result.Stats.HitRatio()
+		row.ByteHitRatio = result.Stats.ByteHitRatio()
+	}
+
+	return row
+}
+
+// WriteCSV writes the Results as CSV, with a header row and one row per Result.
+func WriteCSV(output io.Writer, results []Result) error {
+
+	writer := csv.NewWriter(output)
+
+	if err := writer.Write(csv_header); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		row := result.Row()
+
+		err := writer.Write([]string{
+			row.Trace,
+			row.Policy,
+			strconv.Itoa(row.Capacity),
+			strconv.Itoa(row.SampleSize),
+			strconv.Itoa(row.RequestedSampleSize),
+			strconv.FormatInt(row.Seed, 10),
+			strconv.Itoa(row.Requests),
+			strconv.Itoa(row.Hits),
+			strconv.Itoa(row.Misses),
+			strconv.Itoa(row.HitBytes),
+			strconv.Itoa(row.MissBytes),
+			strconv.Itoa(row.Evictions),
+			strconv.FormatFloat(row.HitRatio, 'g', -1, 64),
+			strconv.FormatFloat(row.ByteHitRatio, 'g', -1, 64),
+			strconv.FormatFloat(row.WallSeconds, 'g', -1, 64),
+			row.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSONLines writes the Results as JSON Lines, one JSON object per Result.
+func WriteJSONLines(output io.Writer, results []Result) error {
+
+	encoder := json.NewEncoder(output)
+
+	for _, result := range results {
+		if err := encoder.Encode(result.Row()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+// Package sim replays cache traces and synthetic workloads against the
+// caching policies in package cache and reports how well each policy does.
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	cache "github.com/jimmytienhoangy/COS316_Project"
+	"github.com/jimmytienhoangy/COS316_Project/trace"
+	"github.com/jimmytienhoangy/COS316_Project/workload"
+)
+
+// RetainedCandidates is the number of eviction candidates the
+// HYPERBOLIC-RETAIN policy carries over from one eviction to the next.
+const RetainedCandidates = 16
+
+// Policies lists the names of the caching policies NewCache can create.
+var Policies = []string{
+	"FIFO", "LRU", "LFU", "LFU-DA", "ARC", "W-TINYLFU", "SIEVE", "S3-FIFO",
+	"CLOCK", "CLOCK-PRO", "HYPERBOLIC", "HYPERBOLIC-RETAIN",
+}
+
+// ErrUnknownPolicy is returned when a caching policy is asked for by a
+// name that is not in Policies.
+var ErrUnknownPolicy = errors.New("unknown caching policy")
+
+// ErrUnknownAdmission is returned when an admission policy is asked for
+// by a name that is not in Admissions, or without a valid parameter.
+var ErrUnknownAdmission = errors.New("unknown admission policy")
+
+// Admissions lists the names of the admission policies NewCache can put
+// in front of a caching policy, by naming the caching policy followed
+// by "+" and the admission policy, such as "LRU+DOORKEEPER". SIZE takes
+// the size of the largest item admitted, such as "LRU+SIZE=4096".
+var Admissions = []string{"DOORKEEPER", "SKETCH", "SIZE"}
+
+// Sampled returns true if the named caching policy samples its eviction
+// candidates, so that its results depend on the sample size and seed.
+// An admission policy after a "+" does not change the answer.
+func Sampled(policy string) bool {
+	policy, _, _ = strings.Cut(policy, "+")
+	return policy == "HYPERBOLIC" || policy == "HYPERBOLIC-RETAIN"
+}
+
+// NewCache creates a new, empty key-only cache that uses the named
+// caching policy, and the admission policy named after a "+", if any.
+// The sample size is only used by sampling policies, and is capped at
+// the max capacity.
+func NewCache(policy string, capacity int, sample_size int, opts ...cache.Option) (cache.KeyCache, error) {
+
+	if sample_size > capacity {
+		sample_size = capacity
+	}
+
+	policy, admission_name, admitted := strings.Cut(policy, "+")
+
+	var created cache.Cache[string, struct{}]
+	var err error
+
+	switch policy {
+	case "FIFO":
+		created, err = cache.NewFIFOCache[string, struct{}](capacity, opts...)
+	case "LRU":
+		created, err = cache.NewLRUCache[string, struct{}](capacity, opts...)
+	case "LFU":
+		created, err = cache.NewLFUCache[string, struct{}](capacity, opts...)
+	case "LFU-DA":
+		created, err = cache.NewLFUCache[string, struct{}](capacity,
+			append(opts, cache.WithDynamicAging())...)
+	case "ARC":
+		created, err = cache.NewARCCache[string, struct{}](capacity, opts...)
+	case "W-TINYLFU":
+		created, err = cache.NewWTinyLFUCache[string, struct{}](capacity, opts...)
+	case "SIEVE":
+		created, err = cache.NewSIEVECache[string, struct{}](capacity, opts...)
+	case "S3-FIFO":
+		created, err = cache.NewS3FIFOCache[string, struct{}](capacity, opts...)
+	case "CLOCK":
+		created, err = cache.NewCLOCKCache[string, struct{}](capacity, opts...)
+	case "CLOCK-PRO":
+		created, err = cache.NewCLOCKProCache[string, struct{}](capacity, opts...)
+	case "HYPERBOLIC":
+		created, err = cache.NewHyperbolicCache[string, struct{}](capacity, sample_size, opts...)
+	case "HYPERBOLIC-RETAIN":
+		created, err = cache.NewHyperbolicCache[string, struct{}](capacity, sample_size,
+			append(opts, cache.WithRetainedCandidates(RetainedCandidates))...)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPolicy, policy)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("creating %s cache: %w", policy, err)
+	}
+
+	if admitted {
+		admission, err := newAdmission(admission_name, capacity, opts...)
+		if err != nil {
+			return nil, err
+		}
+		created = cache.NewAdmissionCache(created, admission)
+	}
+
+	return cache.NewKeyCache(created), nil
+}
+
+// newAdmission creates the named admission policy for a cache of the
+// given max capacity, which counts bytes if the Options say so.
+func newAdmission(name string, capacity int, opts ...cache.Option) (cache.AdmissionPolicy[string], error) {
+
+	policy, parameter, has_parameter := strings.Cut(name, "=")
+
+	switch {
+	case policy == "DOORKEEPER" && !has_parameter:
+		return cache.NewDoorkeeper[string](capacity, opts...), nil
+	case policy == "SKETCH" && !has_parameter:
+		return cache.NewSketchAdmission[string](capacity, opts...), nil
+	case policy == "SIZE" && has_parameter:
+		if max_size, err := strconv.Atoi(parameter); err == nil {
+			return cache.NewSizeThreshold[string](max_size), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownAdmission, name)
+}
+
+// A Source is somewhere the records of an experiment come from, such as
+// a trace file or a synthetic workload.
+type Source interface {
+
+	// Open returns a new reader of the source's records, which must be
+	// closed once the experiment is done.
+	Open() (trace.RecordReader, io.Closer, error)
+
+	// String names the source in results.
+	String() string
+}
+
+// A Trace is a Source that reads a trace file.
+type Trace struct {
+
+	// path of the trace file
+	Path string
+
+	// format of the trace file
+	Format trace.Format
+
+	// whether malformed records are skipped instead of stopping the replay
+	SkipMalformed bool
+}
+
+// Open opens the trace file.
+func (trace_file Trace) Open() (trace.RecordReader, io.Closer, error) {
+
+	file, err := os.Open(trace_file.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reader_opts []trace.ReaderOption
+	if trace_file.SkipMalformed {
+		reader_opts = append(reader_opts, trace.SkipMalformed())
+	}
+
+	records, err := trace.NewFormatReader(trace_file.Format, file, reader_opts...)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", trace_file.Path, err)
+	}
+
+	return records, file, nil
+}
+
+// String returns the path of the trace file.
+func (trace_file Trace) String() string {
+	return trace_file.Path
+}
+
+// A Workload is a Source that generates a synthetic workload.
+type Workload struct {
+
+	// the workload to generate
+	Spec workload.Spec
+
+	// number of requests to generate
+	Requests int
+
+	// size of every requested item
+	ValueSize int
+
+	// seed of the workload's randomness
+	Seed int64
+}
+
+// Open starts generating the workload from its seed, so every
+// experiment on the Workload sees the same requests.
+func (synthetic Workload) Open() (trace.RecordReader, io.Closer, error) {
+
+	generator, err := synthetic.Spec.New(synthetic.Seed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return workload.NewReader(generator, synthetic.Requests, synthetic.ValueSize), io.NopCloser(nil), nil
+}
+
+// String returns the text of the workload's Spec and its seed.
+func (synthetic Workload) String() string {
+	return fmt.Sprintf("%s (seed %d)", synthetic.Spec, synthetic.Seed)
+}
+
+// RunCacheExperiment replays the source's records against a new cache of
+// the given policy, max capacity and (if applicable) sample size, and
+// returns the cache's statistics.
+func RunCacheExperiment(source Source, policy string, capacity int,
+	sample_size int, opts ...cache.Option) (*cache.Stats, error) {
+
+	stats, _, err := runCacheExperiment(source, policy, capacity, sample_size, opts)
+	return stats, err
+}
+
+// runCacheExperiment is RunCacheExperiment, also returning the number of
+// records replayed.
+func runCacheExperiment(source Source, policy string, capacity int,
+	sample_size int, opts []cache.Option) (*cache.Stats, int, error) {
+
+	// open the source of records
+	records, closer, err := source.Open()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer closer.Close()
+
+	// the cache's time is the time of the records
+	clock := cache.NewManualClock(0)
+
+	// create a new cache of the policy
+	keys, err := NewCache(policy, capacity, sample_size, append(opts, cache.WithClock(clock))...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	requests, err := Replay(records, keys, clock)
+	if err != nil {
+		return nil, requests, fmt.Errorf("%s: %w", source, err)
+	}
+
+	return keys.Stats(), requests, nil
+}
+
+// Replay sends every get and set request in a trace to the cache,
+// setting the clock to each request's timestamp first, and returns the
+// number of records it read. A get that misses is followed by a set of
+// the same key, as if the item was fetched from a backing store.
+// Requests other than get and set are skipped.
+func Replay(records trace.RecordReader, keys cache.KeyCache, clock *cache.ManualClock) (requests int, err error) {
+
+	for {
+		record, err := records.Read()
+		if err == io.EOF {
+			return requests, nil
+		}
+		if err != nil {
+			return requests, err
+		}
+
+		requests++
+		clock.Set(record.Timestamp)
+
+		// requests that do not record a size count as 1 byte
+		size := cache.WithSize(max(record.Size(), 1))
+
+		// only handle get and set operations
+		switch record.Operation {
+		case trace.Set:
+			if _, err := keys.Set(record.Key, size, cache.WithTTL(record.TTL)); err != nil {
+				return requests, fmt.Errorf("trace record %d: %w", requests, err)
+			}
+		case trace.Get:
+			// set if get failed
+			if !keys.Get(record.Key, size) {
+				if _, err := keys.Set(record.Key, size); err != nil {
+					return requests, fmt.Errorf("trace record %d: %w", requests, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package sim
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	cache "github.com/jimmytienhoangy/COS316_Project"
+	"github.com/jimmytienhoangy/COS316_Project/trace"
+	"github.com/jimmytienhoangy/COS316_Project/workload"
+)
+
+// trace_text is a small trace in the format of https://github.com/twitter/cache-trace.
+const trace_text = `0,a,1,9,1,get,0
+1,b,1,4,1,get,0
+2,a,1,9,1,get,0
+3,c,1,2,1,set,2
+4,c,1,2,1,get,0
+5,c,1,2,1,delete,0
+6,c,1,2,1,get,0
+`
+
+// Tests that replaying a trace sets items that miss and expires items by
+// the trace's timestamps.
+func Test_Replay(t *testing.T) {
+	clock := cache.NewManualClock(0)
+	keys, err := NewCache("LRU", 10, 0, cache.WithClock(clock))
+	if err != nil {
+		t.Errorf("Failed to create cache: %v", err)
+		t.FailNow()
+	}
+
+	requests, err := Replay(trace.NewReader(strings.NewReader(trace_text)), keys, clock)
+	if err != nil || requests != 7 {
+		t.Errorf("Expected to replay 7 requests, replayed %d: %v", requests, err)
+		t.FailNow()
+	}
+
+	// 'a' hits the second time, 'c' hits at time 4 and expires at time 5
+	stats := keys.Stats()
+	if stats.Hits != 2 || stats.Misses != 3 {
+		t.Errorf("Expected 2 hits and 3 misses, got %d hits and %d misses",
+			stats.Hits, stats.Misses)
+		t.FailNow()
+	}
+
+	if stats.HitBytes != 13 || stats.MissBytes != 18 {
+		t.Errorf("Expected 13 hit bytes and 18 missed bytes, got %d and %d",
+			stats.HitBytes, stats.MissBytes)
+		t.FailNow()
+	}
+
+	if clock.Now() != 6 {
+		t.Errorf("Expected the clock to be at time 6, got %d", clock.Now())
+		t.FailNow()
+	}
+}
+
+// Tests that replaying a malformed trace reports the line it failed on.
+func Test_ReplayMalformed(t *testing.T) {
+	clock := cache.NewManualClock(0)
+	keys, _ := NewCache("FIFO", 10, 0, cache.WithClock(clock))
+
+	_, err := Replay(trace.NewReader(strings.NewReader("0,a,1,9,1,get,0\n1,b,one,9,1,get,0\n")), keys, clock)
+	var parse_error *trace.ParseError
+	if !errors.As(err, &parse_error) || parse_error.Line != 2 {
+		t.Errorf("Expected an error on line 2, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// Tests that every policy can be created by name and run on a trace file.
+func Test_RunCacheExperiment(t *testing.T) {
+	trace_file := Trace{Path: filepath.Join(t.TempDir(), "trace"), Format: trace.Twitter}
+	if err := os.WriteFile(trace_file.Path, []byte(trace_text+"bad\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the malformed last line stops the replay unless it is skipped
+	if _, err := RunCacheExperiment(trace_file, "LRU", 10, 0); !errors.Is(err, trace.ErrFieldCount) {
+		t.Errorf("Expected a malformed trace error, got: %v", err)
+		t.FailNow()
+	}
+	trace_file.SkipMalformed = true
+
+	for _, policy := range Policies {
+		stats, err := RunCacheExperiment(trace_file, policy, 10, 64)
+		if err != nil {
+			t.Errorf("%s: failed to run experiment: %v", policy, err)
+			t.FailNow()
+		}
+
+		if stats.Hits+stats.Misses != 5 {
+			t.Errorf("%s: expected 5 gets, got %d", policy, stats.Hits+stats.Misses)
+			t.FailNow()
+		}
+	}
+
+	if _, err := NewCache("RANDOM", 10, 0); !errors.Is(err, ErrUnknownPolicy) {
+		t.Errorf("Expected an unknown policy error, got: %v", err)
+		t.FailNow()
+	}
+
+	for _, policy := range []string{"LRU+DOORKEEPER", "HYPERBOLIC+SKETCH", "ARC+SIZE=4"} {
+		if _, err := NewCache(policy, 10, 2); err != nil {
+			t.Errorf("Failed to create %s cache: %v", policy, err)
+			t.FailNow()
+		}
+	}
+
+	for _, policy := range []string{"LRU+BLOOM", "LRU+SIZE", "LRU+SIZE=big", "LRU+SKETCH=4"} {
+		if _, err := NewCache(policy, 10, 2); !errors.Is(err, ErrUnknownAdmission) {
+			t.Errorf("Expected an unknown admission policy error for %s, got: %v", policy, err)
+			t.FailNow()
+		}
+	}
+
+	missing := Trace{Path: filepath.Join(t.TempDir(), "missing")}
+	if _, err := RunCacheExperiment(missing, "LRU", 10, 0); err == nil {
+		t.Errorf("Expected an error for a missing trace file.")
+		t.FailNow()
+	}
+}
+
+// Tests that a workload can be run like a trace file, and that every run
+// of it sees the same requests.
+func Test_RunWorkload(t *testing.T) {
+	spec, err := workload.Parse("zipf:keys=1000,skew=0.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	synthetic := Workload{Spec: spec, Requests: 10000, ValueSize: 1, Seed: 316}
+
+	first, err := RunCacheExperiment(synthetic, "HYPERBOLIC", 100, 64)
+	if err != nil {
+		t.Errorf("Failed to run experiment: %v", err)
+		t.FailNow()
+	}
+
+	if first.Hits+first.Misses != 10000 || first.Hits == 0 {
+		t.Errorf("Expected 10000 requests with some hits, got %d hits and %d misses",
+			first.Hits, first.Misses)
+		t.FailNow()
+	}
+
+	// the hyperbolic cache samples with a time-seeded source, so
+	// compare runs of a policy without randomness
+	lru_first, _ := RunCacheExperiment(synthetic, "LRU", 100, 0)
+	lru_second, _ := RunCacheExperiment(synthetic, "LRU", 100, 0)
+	if *lru_first != *lru_second {
+		t.Errorf("Expected every run of the workload to see the same requests.")
+		t.FailNow()
+	}
+}
+
+// Tests that a grid runs every combination of its dimensions, and that
+// its results come back in the same order with the same statistics
+// however many workers run them.
+func Test_RunAll(t *testing.T) {
+	zipf, _ := workload.Parse("zipf:keys=500,skew=0.8")
+	hot_set, _ := workload.Parse("hotset:keys=500,hot=20,period=500")
+
+	grid := Grid{
+		Sources: []Source{
+			Workload{Spec: zipf, Requests: 2000, ValueSize: 1, Seed: 1},
+			Workload{Spec: hot_set, Requests: 2000, ValueSize: 1, Seed: 1},
+		},
+		Policies:    []string{"LRU", "HYPERBOLIC", "RANDOM"},
+		Capacities:  []int{10, 50},
+		SampleSizes: []int{4, 8},
+		Seeds:       []int64{1, 2},
+	}
+
+	// only HYPERBOLIC runs with every sample size and seed
+	experiments := grid.Experiments()
+	if len(experiments) != 2*2*(1+2*2+1) {
+		t.Errorf("Expected %d experiments, got %d", 2*2*(1+2*2+1), len(experiments))
+		t.FailNow()
+	}
+
+	if first, last := experiments[0], experiments[len(experiments)-1]; first.Capacity != 10 ||
+		first.Policy != "LRU" || first.SampleSize != 0 || first.Seed != 0 ||
+		last.Capacity != 50 || last.Policy != "RANDOM" || experiments[len(experiments)-2].SampleSize != 8 {
+		t.Errorf("Expected experiments ordered by capacity first and seed last, got %+v and %+v",
+			first, last)
+		t.FailNow()
+	}
+
+	serial := RunAll(experiments, 1)
+	parallel := RunAll(experiments, 8)
+
+	for i := range experiments {
+		if serial[i].Source.String() != experiments[i].Source.String() ||
+			parallel[i].Policy != experiments[i].Policy || parallel[i].Capacity != experiments[i].Capacity ||
+			parallel[i].SampleSize != experiments[i].SampleSize || parallel[i].Seed != experiments[i].Seed {
+			t.Errorf("Expected result %d to be for experiment %+v", i, experiments[i])
+			t.FailNow()
+		}
+
+		// an unknown policy fails without stopping the others
+		if experiments[i].Policy == "RANDOM" {
+			if !errors.Is(parallel[i].Err, ErrUnknownPolicy) {
+				t.Errorf("Expected an unknown policy error, got: %v", parallel[i].Err)
+				t.FailNow()
+			}
+			continue
+		}
+
+		if parallel[i].Err != nil || *serial[i].Stats != *parallel[i].Stats {
+			t.Errorf("Expected experiment %+v to have the same results however it is run, got %+v and %+v (%v)",
+				experiments[i], serial[i].Stats, parallel[i].Stats, parallel[i].Err)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a grid records the sample size a sampling policy actually
+// runs with alongside the sample size asked for.
+func Test_GridSampleSizes(t *testing.T) {
+	loop, _ := workload.Parse("loop:keys=4")
+	source := Workload{Spec: loop, Requests: 8, ValueSize: 1, Seed: 1}
+
+	grid := Grid{Sources: []Source{source}, Policies: []string{"HYPERBOLIC+DOORKEEPER", "ARC"},
+		Capacities: []int{3}, SampleSizes: []int{2, 3, 64}, Seeds: []int64{5}}
+
+	sample_sizes := []int{}
+	requested := []int{}
+	for _, experiment := range grid.Experiments() {
+		sample_sizes = append(sample_sizes, experiment.SampleSize)
+		requested = append(requested, experiment.RequestedSampleSize)
+	}
+
+	if !slices.Equal(sample_sizes, []int{2, 3, 3, 0}) || !slices.Equal(requested, []int{2, 3, 64, 0}) {
+		t.Errorf("Expected sample sizes 2, 3 and 3 of 2, 3 and 64 for HYPERBOLIC and 0 for ARC, got %v of %v",
+			sample_sizes, requested)
+		t.FailNow()
+	}
+}
+
+// Tests that results are written as CSV and JSON Lines, one row per result.
+func Test_WriteResults(t *testing.T) {
+	loop, _ := workload.Parse("loop:keys=2")
+	source := Workload{Spec: loop, Requests: 6, ValueSize: 3, Seed: 1}
+
+	results := RunAll([]Experiment{
+		{Source: source, Policy: "FIFO", Capacity: 1, SampleSize: 1, RequestedSampleSize: 2, Seed: 1},
+		{Source: source, Policy: "RANDOM", Capacity: 1, SampleSize: 1, RequestedSampleSize: 2, Seed: 1},
+	}, 2)
+
+	var csv_output strings.Builder
+	if err := WriteCSV(&csv_output, results); err != nil {
+		t.Errorf("Failed to write CSV: %v", err)
+		t.FailNow()
+	}
+
+	lines := strings.Split(strings.TrimSpace(csv_output.String()), "\n")
+	if len(lines) != 3 || lines[0] != strings.Join(csv_header, ",") ||
+		!strings.HasPrefix(lines[1], "loop:keys=2 (seed 1),FIFO,1,1,2,1,6,0,6,0,18,5,0,0,") ||
+		!strings.Contains(lines[2], "unknown caching policy") {
+		t.Errorf("Expected a header and 2 rows of CSV, got:\n%s", csv_output.String())
+		t.FailNow()
+	}
+
+	var json_output strings.Builder
+	if err := WriteJSONLines(&json_output, results); err != nil {
+		t.Errorf("Failed to write JSON Lines: %v", err)
+		t.FailNow()
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(json_output.String()))
+	rows := []Row{}
+	for decoder.More() {
+		var row Row
+		if err := decoder.Decode(&row); err != nil {
+			t.Errorf("Failed to decode JSON Lines: %v", err)
+			t.FailNow()
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) != 2 || rows[0].Evictions != 5 || rows[0].MissBytes != 18 || rows[0].Error != "" ||
+		rows[1].Error == "" {
+		t.Errorf("Expected 2 rows, the first with 5 evictions and the second failed, got %+v", rows)
+		t.FailNow()
+	}
+}
+
+// Tests that Curves average over seeds and are plotted as SVG.
+func Test_Plot(t *testing.T) {
+	loop, _ := workload.Parse("loop:keys=4")
+	source := Workload{Spec: loop, Requests: 8, ValueSize: 1, Seed: 1}
+
+	grid := Grid{Sources: []Source{source}, Policies: []string{"LRU", "HYPERBOLIC", "RANDOM"},
+		Capacities: []int{4, 2}, SampleSizes: []int{2}, Seeds: []int64{1, 2}}
+	results := RunAll(grid.Experiments(), 0)
+
+	curves := Curves(results, source.String(), MissRatio)
+	if len(curves) != 2 || curves[0].Name != "LRU" || curves[1].Name != "HYPERBOLIC" {
+		t.Errorf("Expected curves of LRU and HYPERBOLIC, got %+v", curves)
+		t.FailNow()
+	}
+
+	// a loop over more keys than fit in LRU misses every time
+	lru := curves[0]
+	if len(lru.Capacities) != 2 || lru.Capacities[0] != 2 || lru.Capacities[1] != 4 ||
+		lru.Ratios[0] != 1 || lru.Ratios[1] != 0.5 {
+		t.Errorf("Expected LRU miss ratios of 1 and 0.5 at capacities 2 and 4, got %+v", lru)
+		t.FailNow()
+	}
+
+	var svg strings.Builder
+	if err := WriteSVG(&svg, "Miss ratio on <loop>", "Miss ratio", curves); err != nil {
+		t.Errorf("Failed to write SVG: %v", err)
+		t.FailNow()
+	}
+
+	// the plot is well formed XML
+	decoder := xml.NewDecoder(strings.NewReader(svg.String()))
+	polylines := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Errorf("Failed to parse SVG: %v\n%s", err, svg.String())
+			t.FailNow()
+		}
+		if element, ok := token.(xml.StartElement); ok && element.Name.Local == "polyline" {
+			polylines++
+		}
+	}
+
+	if polylines != 2 || !strings.Contains(svg.String(), "Miss ratio on &lt;loop&gt;") {
+		t.Errorf("Expected 2 curves and an escaped title, got:\n%s", svg.String())
+		t.FailNow()
+	}
+}
+
+// Tests that a sample size larger than some of the max capacities still
+// makes a single curve, named without a sample size when it is the only
+// one asked for.
+func Test_PlotCappedSampleSize(t *testing.T) {
+	loop, _ := workload.Parse("loop:keys=4")
+	source := Workload{Spec: loop, Requests: 8, ValueSize: 1, Seed: 1}
+
+	grid := Grid{Sources: []Source{source}, Policies: []string{"HYPERBOLIC"},
+		Capacities: []int{2, 3, 5}, SampleSizes: []int{4}, Seeds: []int64{1}}
+	results := RunAll(grid.Experiments(), 0)
+
+	curves := Curves(results, source.String(), HitRatio)
+	if len(curves) != 1 || curves[0].Name != "HYPERBOLIC" || !slices.Equal(curves[0].Capacities, []int{2, 3, 5}) {
+		t.Errorf("Expected a single HYPERBOLIC curve at capacities 2, 3 and 5, got %+v", curves)
+		t.FailNow()
+	}
+
+	// with more than one sample size, each one asked for has its own curve
+	grid.SampleSizes = []int{2, 4}
+	results = RunAll(grid.Experiments(), 0)
+
+	curves = Curves(results, source.String(), HitRatio)
+	if len(curves) != 2 || curves[0].Name != "HYPERBOLIC [sample size 2]" ||
+		curves[1].Name != "HYPERBOLIC [sample size 4]" || len(curves[1].Capacities) != 3 {
+		t.Errorf("Expected a curve at every capacity for sample sizes 2 and 4, got %+v", curves)
+		t.FailNow()
+	}
+}
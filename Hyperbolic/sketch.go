@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+)
+
+// rows of counters in a frequencySketch, each indexed by its own hash
+const sketch_depth = 4
+
+// largest number of counters in each row of a frequencySketch
+const max_sketch_width = 1 << 22
+
+// largest value of a 4-bit counter
+const max_sketch_count = 15
+
+// A frequencySketch is a count-min sketch of 4-bit counters that
+// estimates how often keys were recently accessed, in much less memory
+// than counting every key. After every sample_size accesses it halves
+// every counter, so that the estimates follow changes in popularity.
+type frequencySketch struct {
+
+	// counters of every row, packed 16 to a word
+	table []uint64
+
+	// number of counters in each row, a power of two
+	width int
+
+	// number of accesses after which every counter is halved
+	sample_size int
+
+	// number of accesses since counters were last halved
+	additions int
+}
+
+// newFrequencySketch returns an empty frequencySketch sized to tell
+// apart the access counts of about the given number of keys, which
+// halves its counters after 10 accesses per key.
+func newFrequencySketch(keys int) *frequencySketch {
+
+	keys = max(keys, 1)
+	width := sketchWidth(keys)
+
+	return &frequencySketch{
+		table:       make([]uint64, sketch_depth*width/16),
+		width:       width,
+		sample_size: 10 * keys,
+		additions:   0,
+	}
+}
+
+// sketchWidth returns the number of counters in each row of a
+// frequencySketch sized for about the given number of keys.
+func sketchWidth(keys int) int {
+
+	// four counters per key in each row keeps collisions rare
+	width := 4 << bits.Len(uint(keys-1))
+
+	return min(max(width, 16), max_sketch_width)
+}
+
+// grow resizes the frequencySketch to tell apart the access counts of
+// about the given number of keys, if it was sized for fewer. The counts
+// are kept while the rows are wide enough, and start over once the
+// sketch needs wider rows.
+func (sketch *frequencySketch) grow(keys int) {
+
+	if 10*keys <= sketch.sample_size {
+		return
+	}
+
+	if width := sketchWidth(keys); width != sketch.width {
+		*sketch = *newFrequencySketch(keys)
+		return
+	}
+
+	sketch.sample_size = 10 * keys
+}
+
+// increment counts an access of the key with the given hash,
+// halving every counter if the sample is full.
+func (sketch *frequencySketch) increment(hash uint64) {
+
+	for row := 0; row < sketch_depth; row++ {
+		word, shift := sketch.counter(hash, row)
+		if (sketch.table[word]>>shift)&max_sketch_count < max_sketch_count {
+			sketch.table[word] += 1 << shift
+		}
+	}
+
+	sketch.additions++
+	if sketch.additions >= sketch.sample_size {
+		sketch.reset()
+	}
+}
+
+// estimate returns the estimated number of recent accesses of the key
+// with the given hash, which is never less than the true number unless
+// the counters were halved or reached their largest value.
+func (sketch *frequencySketch) estimate(hash uint64) int {
+
+	count := max_sketch_count
+	for row := 0; row < sketch_depth; row++ {
+		word, shift := sketch.counter(hash, row)
+		count = min(count, int((sketch.table[word]>>shift)&max_sketch_count))
+	}
+
+	return count
+}
+
+// reset halves every counter, rounding down.
+func (sketch *frequencySketch) reset() {
+
+	for i := range sketch.table {
+		sketch.table[i] = (sketch.table[i] >> 1) & 0x7777777777777777
+	}
+
+	sketch.additions /= 2
+}
+
+// counter returns the word and bit offset of the counter of the key
+// with the given hash in the given row.
+func (sketch *frequencySketch) counter(hash uint64, row int) (word int, shift int) {
+
+	index := row*sketch.width + int(rowHash(hash, row)&uint64(sketch.width-1))
+
+	return index / 16, (index % 16) * 4
+}
+
+// rowHash mixes the row number into a key's hash, giving every row of a
+// sketch or filter its own independent index for the key.
+func rowHash(hash uint64, row int) uint64 {
+
+	mixed := hash + uint64(row+1)*0x9e3779b97f4a7c15
+	mixed = (mixed ^ (mixed >> 30)) * 0xbf58476d1ce4e5b9
+	mixed = (mixed ^ (mixed >> 27)) * 0x94d049bb133111eb
+
+	return mixed ^ (mixed >> 31)
+}
+
+// FNV-1a offset basis and prime, as used by hash/fnv
+const (
+	fnv_offset = 14695981039346656037
+	fnv_prime  = 1099511628211
+)
+
+// keyHash returns a hash of the key that is the same every run, so
+// that caches whose decisions depend on it are reproducible.
+func keyHash[K comparable](key K) uint64 {
+
+	// hash strings and integers by their bytes, without
+	// allocating, and only format keys of other types
+	var number uint64
+	switch typed := any(key).(type) {
+	case string:
+		hash := uint64(fnv_offset)
+		for i := 0; i < len(typed); i++ {
+			hash = (hash ^ uint64(typed[i])) * fnv_prime
+		}
+		return hash
+	case int:
+		number = uint64(typed)
+	case int8:
+		number = uint64(typed)
+	case int16:
+		number = uint64(typed)
+	case int32:
+		number = uint64(typed)
+	case int64:
+		number = uint64(typed)
+	case uint:
+		number = uint64(typed)
+	case uint8:
+		number = uint64(typed)
+	case uint16:
+		number = uint64(typed)
+	case uint32:
+		number = uint64(typed)
+	case uint64:
+		number = typed
+	case uintptr:
+		number = uint64(typed)
+	default:
+		hasher := fnv.New64a()
+		fmt.Fprint(hasher, key)
+		return hasher.Sum64()
+	}
+
+	// hash the integer's 8 bytes, least significant first
+	hash := uint64(fnv_offset)
+	for i := 0; i < 8; i++ {
+		hash = (hash ^ (number & 0xff)) * fnv_prime
+		number >>= 8
+	}
+
+	return hash
+}
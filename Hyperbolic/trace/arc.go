@@ -0,0 +1,54 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ARCBlockSize is the size, in bytes, of the blocks in ARC traces.
+const ARCBlockSize = 512
+
+// NewARCReader returns a Reader that reads Records from a block trace in
+// the format used by the ARC and UMass traces, one request per line:
+//
+//	starting block, number of blocks, ignored, request number
+//
+// with fields separated by spaces. Each request reads its blocks in
+// order, so a line becomes one Get of ARCBlockSize bytes per block,
+// keyed by block number and timestamped by request number.
+func NewARCReader(trace io.Reader, opts ...ReaderOption) *Reader {
+	return newLineReader(trace, parseARCRecords, opts)
+}
+
+// parseARCRecords reads a single line of an ARC trace as the Records
+// of the blocks it requests. The Records are made as they are read,
+// since a line can request any number of blocks.
+func parseARCRecords(line string) (int, func(i int) Record, error) {
+
+	fields := strings.Fields(line)
+	if len(fields) != 4 {
+		return 0, nil, fmt.Errorf("%w: expected 4, got %d", ErrFieldCount, len(fields))
+	}
+
+	// convert the numeric fields to ints
+	numbers := make([]int, 4)
+	for i, name := range []string{"starting block", "number of blocks", "ignored", "request number"} {
+		value, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return 0, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		numbers[i] = value
+	}
+
+	start, count, request := numbers[0], numbers[1], numbers[3]
+	if count < 0 {
+		return 0, nil, fmt.Errorf("number of blocks %d: %w", count, ErrNegativeValue)
+	}
+
+	return count, func(i int) Record {
+		return Record{Timestamp: request, Key: strconv.Itoa(start + i),
+			ValueSize: ARCBlockSize, Operation: Get}
+	}, nil
+}
@@ -0,0 +1,44 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// magic numbers at the start of compressed traces: gzip's is followed
+// by its only compression method, deflate, and bzip2's by a block size
+// from 1 to 9, so that binary traces are not taken for them by chance
+var (
+	gzip_magic  = []byte{0x1f, 0x8b, 0x08}
+	bzip2_magic = []byte("BZh")
+)
+
+// Decompress returns a reader of the decompressed trace if the trace
+// starts with the magic number of gzip or bzip2, or else of the trace
+// itself. Compressed traces are decompressed as they are read.
+func Decompress(trace io.Reader) (io.Reader, error) {
+
+	buffered := bufio.NewReader(trace)
+
+	// a short or empty trace is not compressed
+	magic, _ := buffered.Peek(len(bzip2_magic) + 1)
+
+	switch {
+	case bytes.HasPrefix(magic, gzip_magic):
+		decompressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("reading gzip trace: %w", err)
+		}
+		return decompressed, nil
+
+	case bytes.HasPrefix(magic, bzip2_magic) && len(magic) > len(bzip2_magic) &&
+		'1' <= magic[len(bzip2_magic)] && magic[len(bzip2_magic)] <= '9':
+		return bzip2.NewReader(buffered), nil
+	}
+
+	return buffered, nil
+}
@@ -0,0 +1,67 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// A Format is a trace format this package can read.
+type Format int
+
+// Formats this package can read.
+const (
+	Twitter Format = iota
+	ARC
+	MSR
+	OracleGeneral
+)
+
+// format_names maps each Format to its name.
+var format_names = []string{"twitter", "arc", "msr", "oracle-general"}
+
+// String returns the name of the Format.
+func (format Format) String() string {
+	if format < 0 || int(format) >= len(format_names) {
+		return "Format(" + strconv.Itoa(int(format)) + ")"
+	}
+	return format_names[format]
+}
+
+// ParseFormat returns the Format with the given name.
+func ParseFormat(name string) (Format, error) {
+	for format, format_name := range format_names {
+		if name == format_name {
+			return Format(format), nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q", ErrUnknownFormat, name)
+}
+
+// NewFormatReader returns a RecordReader that reads Records from a trace
+// in the given Format. Traces compressed with gzip or bzip2 are
+// detected and decompressed as they are read.
+func NewFormatReader(format Format, trace io.Reader, opts ...ReaderOption) (RecordReader, error) {
+
+	if format < 0 || int(format) >= len(format_names) {
+		return nil, fmt.Errorf("%w %s", ErrUnknownFormat, format)
+	}
+
+	trace, err := Decompress(trace)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case Twitter:
+		return NewReader(trace, opts...), nil
+	case ARC:
+		return NewARCReader(trace, opts...), nil
+	case MSR:
+		return NewMSRReader(trace, opts...), nil
+	case OracleGeneral:
+		return NewOracleGeneralReader(trace, opts...), nil
+	}
+
+	return nil, fmt.Errorf("%w %s", ErrUnknownFormat, format)
+}
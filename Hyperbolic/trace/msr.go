@@ -0,0 +1,74 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// filetime_ticks_per_second is the number of Windows filetime ticks,
+// which MSR Cambridge traces are timestamped in, per second.
+const filetime_ticks_per_second = 10000000
+
+// NewMSRReader returns a Reader that reads Records from a block trace in
+// the MSR Cambridge CSV format, one request per line:
+//
+//	timestamp,hostname,disk number,type,offset,size,response time
+//
+// Reads become Gets and writes become Sets of the requested bytes, keyed
+// by hostname, disk number and offset. Timestamps, which MSR traces
+// record in Windows filetime ticks, are converted to seconds, and the
+// disk number is the client id.
+func NewMSRReader(trace io.Reader, opts ...ReaderOption) *Reader {
+	return newLineReader(trace, parseMSRRecords, opts)
+}
+
+// parseMSRRecords reads a single line of a MSR Cambridge trace as a Record.
+func parseMSRRecords(line string) (int, func(i int) Record, error) {
+
+	fields := strings.Split(line, ",")
+	if len(fields) != 7 {
+		return 0, nil, fmt.Errorf("%w: expected 7, got %d", ErrFieldCount, len(fields))
+	}
+
+	hostname, request_type := fields[1], fields[3]
+
+	// convert the numeric fields to ints
+	numbers := make([]int, 7)
+	for _, i := range []int{0, 2, 4, 5} {
+		value, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return 0, nil, fmt.Errorf("field %d: %w", i+1, err)
+		}
+		numbers[i] = value
+	}
+
+	timestamp, disk, offset, size := numbers[0], numbers[2], numbers[4], numbers[5]
+
+	// make sure that offsets and sizes are not negative
+	if offset < 0 {
+		return 0, nil, fmt.Errorf("offset %d: %w", offset, ErrNegativeValue)
+	}
+	if size < 0 {
+		return 0, nil, fmt.Errorf("size %d: %w", size, ErrNegativeValue)
+	}
+
+	var operation Operation
+	switch request_type {
+	case "Read":
+		operation = Get
+	case "Write":
+		operation = Set
+	default:
+		return 0, nil, fmt.Errorf("%w %q", ErrUnknownOperation, request_type)
+	}
+
+	return singleRecord(Record{
+		Timestamp: timestamp / filetime_ticks_per_second,
+		Key:       hostname + ":" + strconv.Itoa(disk) + ":" + strconv.Itoa(offset),
+		ValueSize: size,
+		ClientID:  disk,
+		Operation: operation,
+	})
+}
@@ -0,0 +1,93 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// oracle_general_record_size is the size, in bytes, of a single record
+// in a libCacheSim oracleGeneral trace.
+const oracle_general_record_size = 24
+
+// An OracleGeneralReader reads Records from a binary trace in
+// libCacheSim's oracleGeneral format: 24-byte little-endian records of
+//
+//	uint32 timestamp, uint64 object id, uint32 object size,
+//	int64 logical time of the next request for the object
+//
+// Every record becomes a Get of the object, keyed by object id. The
+// time of the next request is not part of the Record.
+type OracleGeneralReader struct {
+
+	// buffered trace
+	trace *bufio.Reader
+
+	// the last record read
+	buffer [oracle_general_record_size]byte
+
+	// number of the last record read
+	record int
+
+	// whether malformed records are skipped instead of returned as errors
+	skip_malformed bool
+
+	// number of malformed records skipped
+	skipped int
+}
+
+// NewOracleGeneralReader returns an OracleGeneralReader that reads
+// Records from the given trace.
+func NewOracleGeneralReader(trace io.Reader, opts ...ReaderOption) *OracleGeneralReader {
+
+	config := newReaderOptions(opts)
+
+	return &OracleGeneralReader{
+		trace:          bufio.NewReader(trace),
+		skip_malformed: config.skip_malformed,
+	}
+}
+
+// Read returns the next Record in the trace, or io.EOF once every record
+// has been read. A trace that ends partway through a record is returned
+// as a *ParseError, unless the OracleGeneralReader skips malformed records.
+func (reader *OracleGeneralReader) Read() (Record, error) {
+
+	_, err := io.ReadFull(reader.trace, reader.buffer[:])
+	if err == io.EOF {
+		return Record{}, io.EOF
+	}
+
+	reader.record++
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		if reader.skip_malformed {
+			reader.skipped++
+			return Record{}, io.EOF
+		}
+		return Record{}, &ParseError{Line: reader.record, Err: err}
+	}
+
+	if err != nil {
+		return Record{}, err
+	}
+
+	return Record{
+		Timestamp: int(binary.LittleEndian.Uint32(reader.buffer[0:4])),
+		Key:       strconv.FormatUint(binary.LittleEndian.Uint64(reader.buffer[4:12]), 10),
+		ValueSize: int(binary.LittleEndian.Uint32(reader.buffer[12:16])),
+		Operation: Get,
+	}, nil
+}
+
+// Line returns the number of the last record read, starting from 1.
+func (reader *OracleGeneralReader) Line() int {
+	return reader.record
+}
+
+// Skipped returns the number of malformed records skipped so far.
+func (reader *OracleGeneralReader) Skipped() int {
+	return reader.skipped
+}
@@ -0,0 +1,333 @@
+// Package trace reads cache traces as streams of Records. NewReader
+// reads traces in the format of https://github.com/twitter/cache-trace,
+// one request per line:
+//
+//	timestamp,anonymized key,key size,value size,client id,operation,TTL
+//
+// and the other readers in this package read block and CDN trace
+// formats into the same Records.
+package trace
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrFieldCount is returned when a line of a trace does not
+	// have the number of fields a record needs.
+	ErrFieldCount = errors.New("wrong number of fields")
+
+	// ErrUnknownOperation is returned when a line of a trace
+	// has an operation that is not one of the Operations.
+	ErrUnknownOperation = errors.New("unknown operation")
+
+	// ErrNegativeValue is returned when a line of a trace
+	// has a negative value where it can not have one.
+	ErrNegativeValue = errors.New("negative value")
+
+	// ErrUnknownFormat is returned when a trace format is asked
+	// for by a name that is not one of the Formats.
+	ErrUnknownFormat = errors.New("unknown trace format")
+)
+
+// An Operation is the kind of request a record makes of the cache.
+type Operation int
+
+// Operations a trace can record, named after memcached's commands.
+const (
+	Get Operation = iota
+	Gets
+	Set
+	Add
+	Replace
+	CAS
+	Append
+	Prepend
+	Delete
+	Incr
+	Decr
+)
+
+// operation_names maps each Operation to its name in a trace.
+var operation_names = []string{"get", "gets", "set", "add", "replace", "cas",
+	"append", "prepend", "delete", "incr", "decr"}
+
+// String returns the name of the Operation in a trace.
+func (operation Operation) String() string {
+	if operation < 0 || int(operation) >= len(operation_names) {
+		return "Operation(" + strconv.Itoa(int(operation)) + ")"
+	}
+	return operation_names[operation]
+}
+
+// ParseOperation returns the Operation with the given name in a trace.
+func ParseOperation(name string) (Operation, error) {
+	for operation, operation_name := range operation_names {
+		if name == operation_name {
+			return Operation(operation), nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q", ErrUnknownOperation, name)
+}
+
+// A RecordReader reads the Records of a trace in order. Read returns
+// io.EOF once every Record has been read, and a *ParseError for a part
+// of the trace that can not be read as a Record.
+type RecordReader interface {
+	Read() (Record, error)
+}
+
+// A Record is a single request in a trace.
+type Record struct {
+
+	// time of the request, in seconds, or its position
+	// in traces that do not record time
+	Timestamp int
+
+	// anonymized key of the requested item
+	Key string
+
+	// size of the key, in bytes
+	KeySize int
+
+	// size of the value, in bytes
+	ValueSize int
+
+	// anonymized id of the client that made the request
+	ClientID int
+
+	// kind of request
+	Operation Operation
+
+	// time to live of the item, in seconds, or 0 if it never expires
+	TTL int
+}
+
+// Size returns the total size of the requested item's key and value.
+func (record Record) Size() int {
+	return record.KeySize + record.ValueSize
+}
+
+// A ParseError is returned when a line of a trace can not be read as a Record.
+type ParseError struct {
+
+	// number of the line, or of the record in binary traces,
+	// starting from 1
+	Line int
+
+	// why the line could not be read
+	Err error
+}
+
+// Error returns the line number and why the line could not be read.
+func (err *ParseError) Error() string {
+	return fmt.Sprintf("trace line %d: %v", err.Line, err.Err)
+}
+
+// Unwrap returns why the line could not be read.
+func (err *ParseError) Unwrap() error {
+	return err.Err
+}
+
+// A ReaderOption changes how a trace reader reads a trace.
+type ReaderOption func(*reader_options)
+
+// reader_options holds the configuration that can be changed with ReaderOptions.
+type reader_options struct {
+
+	// whether malformed lines are skipped instead of returned as errors
+	skip_malformed bool
+}
+
+// newReaderOptions returns the default configuration with the given
+// ReaderOptions applied.
+func newReaderOptions(opts []ReaderOption) reader_options {
+
+	var config reader_options
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return config
+}
+
+// SkipMalformed makes a trace reader skip lines (or records, in binary
+// traces) that can not be read as a Record instead of returning a
+// ParseError. Skipped counts them.
+func SkipMalformed() ReaderOption {
+	return func(config *reader_options) {
+		config.skip_malformed = true
+	}
+}
+
+// A Reader reads Records from a text trace one line at a time.
+type Reader struct {
+
+	// scanner over the lines of the trace
+	scanner *bufio.Scanner
+
+	// reads a single line of the trace as the number of Records it
+	// holds and a function that makes the i-th of them
+	parse lineParser
+
+	// makes the Records of the last line read
+	pending func(i int) Record
+
+	// index of the next Record of the last line to return
+	next int
+
+	// number of Records the last line holds
+	count int
+
+	// number of the last line read
+	line int
+
+	// whether malformed lines are skipped instead of returned as errors
+	skip_malformed bool
+
+	// number of malformed lines skipped
+	skipped int
+}
+
+// NewReader returns a Reader that reads Records from the given trace
+// in the format of https://github.com/twitter/cache-trace.
+func NewReader(trace io.Reader, opts ...ReaderOption) *Reader {
+	return newLineReader(trace, parseRecords, opts)
+}
+
+// A lineParser reads a single line of a text trace as the number of
+// Records it holds and a function that makes the i-th of them. Records
+// are made one at a time as they are read, so that a short line can
+// hold more Records than fit in memory at once.
+type lineParser func(line string) (count int, record func(i int) Record, err error)
+
+// singleRecord returns a line of a text trace that holds only the record.
+func singleRecord(record Record) (int, func(i int) Record, error) {
+	return 1, func(int) Record { return record }, nil
+}
+
+// newLineReader returns a Reader that reads Records from the given
+// trace with the given parse function.
+func newLineReader(trace io.Reader, parse lineParser, opts []ReaderOption) *Reader {
+
+	config := newReaderOptions(opts)
+
+	return &Reader{
+		scanner:        bufio.NewScanner(trace),
+		parse:          parse,
+		skip_malformed: config.skip_malformed,
+	}
+}
+
+// Read returns the next Record in the trace, or io.EOF once every line
+// has been read. A line that can not be read as Records is returned as
+// a *ParseError, unless the Reader skips malformed lines; the Reader can
+// keep reading after it.
+func (reader *Reader) Read() (Record, error) {
+
+	// lines can hold more than one Record
+	if reader.next < reader.count {
+		reader.next++
+		return reader.pending(reader.next - 1), nil
+	}
+
+	for reader.scanner.Scan() {
+		reader.line++
+
+		count, record, err := reader.parse(reader.scanner.Text())
+		if err == nil && count > 0 {
+			reader.pending, reader.next, reader.count = record, 1, count
+			return record(0), nil
+		}
+
+		if err == nil {
+			continue
+		}
+
+		if !reader.skip_malformed {
+			return Record{}, &ParseError{Line: reader.line, Err: err}
+		}
+
+		reader.skipped++
+	}
+
+	if err := reader.scanner.Err(); err != nil {
+		return Record{}, err
+	}
+
+	return Record{}, io.EOF
+}
+
+// Line returns the number of the last line read, starting from 1.
+func (reader *Reader) Line() int {
+	return reader.line
+}
+
+// Skipped returns the number of malformed lines skipped so far.
+func (reader *Reader) Skipped() int {
+	return reader.skipped
+}
+
+// parseRecords reads a single line of a Twitter trace as a Record.
+func parseRecords(line string) (int, func(i int) Record, error) {
+
+	record, err := parseRecord(line)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return singleRecord(record)
+}
+
+// parseRecord reads a single line of a Twitter trace as a Record.
+func parseRecord(line string) (Record, error) {
+
+	var record Record
+
+	fields := strings.Split(line, ",")
+	if len(fields) != 7 {
+		return record, fmt.Errorf("%w: expected 7, got %d", ErrFieldCount, len(fields))
+	}
+
+	record.Key = fields[1]
+
+	// convert the numeric fields to ints, and make sure
+	// that sizes and TTLs are not negative
+	numbers := []struct {
+		name         string
+		field        string
+		value        *int
+		non_negative bool
+	}{
+		{"timestamp", fields[0], &record.Timestamp, false},
+		{"key size", fields[2], &record.KeySize, true},
+		{"value size", fields[3], &record.ValueSize, true},
+		{"client id", fields[4], &record.ClientID, false},
+		{"ttl", fields[6], &record.TTL, true},
+	}
+
+	for _, number := range numbers {
+		value, err := strconv.Atoi(number.field)
+		if err != nil {
+			return record, fmt.Errorf("%s: %w", number.name, err)
+		}
+		if number.non_negative && value < 0 {
+			return record, fmt.Errorf("%s %d: %w", number.name, value, ErrNegativeValue)
+		}
+		*number.value = value
+	}
+
+	operation, err := ParseOperation(fields[5])
+	if err != nil {
+		return record, err
+	}
+	record.Operation = operation
+
+	return record, nil
+}
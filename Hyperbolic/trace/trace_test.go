@@ -0,0 +1,328 @@
+package trace
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Tests that a Reader reads every field of every record.
+func Test_Read(t *testing.T) {
+	reader := NewReader(strings.NewReader("0,a,1,9,2,get,0\n5,b,3,4,7,set,60\n"))
+
+	expected := []Record{
+		{Timestamp: 0, Key: "a", KeySize: 1, ValueSize: 9, ClientID: 2, Operation: Get, TTL: 0},
+		{Timestamp: 5, Key: "b", KeySize: 3, ValueSize: 4, ClientID: 7, Operation: Set, TTL: 60},
+	}
+
+	for i, want := range expected {
+		record, err := reader.Read()
+		if err != nil || record != want {
+			t.Errorf("Expected record %+v on line %d, got %+v (%v)", want, i+1, record, err)
+			t.FailNow()
+		}
+	}
+
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got: %v", err)
+		t.FailNow()
+	}
+
+	if expected[1].Size() != 7 {
+		t.Errorf("Expected a size of 7, got %d", expected[1].Size())
+		t.FailNow()
+	}
+}
+
+// Tests that a strict Reader reports malformed lines with their line
+// numbers and can keep reading after them.
+func Test_ReadMalformed(t *testing.T) {
+	lines := "0,a,1,9,2,get,0\n1,b,1,9\n2,c,one,9,2,get,0\n3,d,1,9,2,fetch,0\n" +
+		"4,e,-1,9,2,get,0\n5,f,1,-9,2,get,0\n6,g,1,9,2,set,-1\n7,h,1,9,2,gets,0\n"
+	reader := NewReader(strings.NewReader(lines))
+
+	reader.Read()
+
+	for i, cause := range []error{ErrFieldCount, strconv.ErrSyntax, ErrUnknownOperation,
+		ErrNegativeValue, ErrNegativeValue, ErrNegativeValue} {
+		_, err := reader.Read()
+
+		var parse_error *ParseError
+		if !errors.As(err, &parse_error) || parse_error.Line != i+2 || !errors.Is(err, cause) {
+			t.Errorf("Expected a parse error on line %d caused by %v, got: %v", i+2, cause, err)
+			t.FailNow()
+		}
+	}
+
+	if record, err := reader.Read(); err != nil || record.Operation != Gets {
+		t.Errorf("Expected to keep reading after malformed lines, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// Tests that a Reader that skips malformed lines counts them.
+func Test_ReadSkipMalformed(t *testing.T) {
+	reader := NewReader(strings.NewReader("0,a,1,9,2,get,0\nbad\n\n2,c,1,9,2,delete,0\n"), SkipMalformed())
+
+	keys := []string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Errorf("Expected malformed lines to be skipped, got: %v", err)
+			t.FailNow()
+		}
+		keys = append(keys, record.Key)
+	}
+
+	if strings.Join(keys, ",") != "a,c" || reader.Skipped() != 2 || reader.Line() != 4 {
+		t.Errorf("Expected keys a,c with 2 lines skipped out of 4, got %v with %d skipped out of %d",
+			keys, reader.Skipped(), reader.Line())
+		t.FailNow()
+	}
+}
+
+// Tests that Operations are named as they are in traces.
+func Test_Operation(t *testing.T) {
+	for _, operation := range []Operation{Get, Gets, Set, Add, Replace, CAS, Append, Prepend, Delete, Incr, Decr} {
+		parsed, err := ParseOperation(operation.String())
+		if err != nil || parsed != operation {
+			t.Errorf("Expected %s to parse back to itself, got %s (%v)", operation, parsed, err)
+			t.FailNow()
+		}
+	}
+
+	if name := Operation(99).String(); name != "Operation(99)" {
+		t.Errorf("Expected an unknown operation to be named Operation(99), got %s", name)
+		t.FailNow()
+	}
+}
+
+// Tests that an ARC reader reads one record per requested block.
+func Test_ReadARC(t *testing.T) {
+	reader := NewARCReader(strings.NewReader("100 2 0 1\n7 1 0 2\n"))
+
+	for _, want := range []Record{
+		{Timestamp: 1, Key: "100", ValueSize: ARCBlockSize, Operation: Get},
+		{Timestamp: 1, Key: "101", ValueSize: ARCBlockSize, Operation: Get},
+		{Timestamp: 2, Key: "7", ValueSize: ARCBlockSize, Operation: Get},
+	} {
+		record, err := reader.Read()
+		if err != nil || record != want {
+			t.Errorf("Expected record %+v, got %+v (%v)", want, record, err)
+			t.FailNow()
+		}
+	}
+
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewARCReader(strings.NewReader("1 -1 0 1\n")).Read(); !errors.Is(err, ErrNegativeValue) {
+		t.Errorf("Expected a negative value error, got: %v", err)
+		t.FailNow()
+	}
+
+	// a line that requests more blocks than fit in memory is read
+	// one block at a time
+	reader = NewARCReader(strings.NewReader("0 2000000000 0 1\n"))
+	for i := 0; i < 3; i++ {
+		record, err := reader.Read()
+		if err != nil || record.Key != strconv.Itoa(i) {
+			t.Errorf("Expected block %d, got %+v (%v)", i, record, err)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that a MSR Cambridge reader reads reads and writes.
+func Test_ReadMSR(t *testing.T) {
+	reader := NewMSRReader(strings.NewReader(
+		"128166372003061629,hm,1,Read,3862528,4096,1130\n" +
+			"128166372016382155,hm,0,Write,3862528,512,2000\n" +
+			"128166372016382155,hm,0,Trim,3862528,512,2000\n" +
+			"128166372016382155,hm,0,Read,-512,512,2000\n" +
+			"128166372016382155,hm,0,Read,3862528,-512,2000\n"))
+
+	for _, want := range []Record{
+		{Timestamp: 12816637200, Key: "hm:1:3862528", ValueSize: 4096, ClientID: 1, Operation: Get},
+		{Timestamp: 12816637201, Key: "hm:0:3862528", ValueSize: 512, ClientID: 0, Operation: Set},
+	} {
+		record, err := reader.Read()
+		if err != nil || record != want {
+			t.Errorf("Expected record %+v, got %+v (%v)", want, record, err)
+			t.FailNow()
+		}
+	}
+
+	// malformed lines are reported with their line numbers
+	for i, cause := range []error{ErrUnknownOperation, ErrNegativeValue, ErrNegativeValue} {
+		_, err := reader.Read()
+
+		var parse_error *ParseError
+		if !errors.As(err, &parse_error) || parse_error.Line != i+3 || !errors.Is(err, cause) {
+			t.Errorf("Expected a parse error on line %d caused by %v, got: %v", i+3, cause, err)
+			t.FailNow()
+		}
+	}
+}
+
+// oracleGeneralRecord encodes a single record of an oracleGeneral trace.
+func oracleGeneralRecord(timestamp uint32, id uint64, size uint32, next int64) []byte {
+	record := make([]byte, oracle_general_record_size)
+	binary.LittleEndian.PutUint32(record[0:4], timestamp)
+	binary.LittleEndian.PutUint64(record[4:12], id)
+	binary.LittleEndian.PutUint32(record[12:16], size)
+	binary.LittleEndian.PutUint64(record[16:24], uint64(next))
+	return record
+}
+
+// Tests that an oracleGeneral reader reads binary records and reports
+// a trace that ends partway through a record.
+func Test_ReadOracleGeneral(t *testing.T) {
+	trace := append(oracleGeneralRecord(3, 42, 1024, 9), oracleGeneralRecord(4, 1<<40, 8, -1)...)
+
+	for _, skip := range []bool{false, true} {
+		var opts []ReaderOption
+		if skip {
+			opts = append(opts, SkipMalformed())
+		}
+		reader := NewOracleGeneralReader(bytes.NewReader(append(trace, 1, 2, 3)), opts...)
+
+		for _, want := range []Record{
+			{Timestamp: 3, Key: "42", ValueSize: 1024, Operation: Get},
+			{Timestamp: 4, Key: "1099511627776", ValueSize: 8, Operation: Get},
+		} {
+			record, err := reader.Read()
+			if err != nil || record != want {
+				t.Errorf("Expected record %+v, got %+v (%v)", want, record, err)
+				t.FailNow()
+			}
+		}
+
+		_, err := reader.Read()
+		var parse_error *ParseError
+		if skip && (err != io.EOF || reader.Skipped() != 1) {
+			t.Errorf("Expected the partial record to be skipped, got: %v", err)
+			t.FailNow()
+		}
+		if !skip && (!errors.As(err, &parse_error) || parse_error.Line != 3) {
+			t.Errorf("Expected a parse error on record 3, got: %v", err)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that every Format can be named and read from.
+func Test_Format(t *testing.T) {
+	for _, format := range []Format{Twitter, ARC, MSR, OracleGeneral} {
+		parsed, err := ParseFormat(format.String())
+		if err != nil || parsed != format {
+			t.Errorf("Expected %s to parse back to itself, got %s (%v)", format, parsed, err)
+			t.FailNow()
+		}
+
+		reader, err := NewFormatReader(format, strings.NewReader(""))
+		if err != nil {
+			t.Errorf("Failed to create a %s reader: %v", format, err)
+			t.FailNow()
+		}
+
+		if _, err := reader.Read(); err != io.EOF {
+			t.Errorf("Expected io.EOF from an empty %s trace, got: %v", format, err)
+			t.FailNow()
+		}
+	}
+
+	if _, err := ParseFormat("csv"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected an unknown format error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewFormatReader(Format(99), strings.NewReader("")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected an unknown format error, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// compressed_text is the Twitter trace read by Test_Decompress.
+const compressed_text = "0,a,1,9,2,get,0\n5,b,3,4,7,set,60\n"
+
+// bzip2_trace is compressed_text compressed with bzip2, which the
+// standard library can only decompress.
+var bzip2_trace = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x5e, 0x8a, 0xad, 0x73, 0x00, 0x00,
+	0x08, 0x59, 0x80, 0x00, 0x10, 0x00, 0x04, 0x7f, 0xa0, 0x32, 0x80, 0x0c, 0x00, 0x20, 0x00, 0x31,
+	0x43, 0x4d, 0x30, 0x00, 0x35, 0x32, 0x34, 0x34, 0xcd, 0x46, 0x8a, 0x2a, 0x64, 0xbe, 0x38, 0xfa,
+	0x88, 0x24, 0x94, 0x01, 0x12, 0xc3, 0xd4, 0xa2, 0x87, 0xc5, 0xdc, 0x91, 0x4e, 0x14, 0x24, 0x17,
+	0xa2, 0xab, 0x5c, 0xc0,
+}
+
+// Tests that gzip and bzip2 traces are detected and decompressed, and
+// that other traces are read as they are.
+func Test_Decompress(t *testing.T) {
+	var gzip_trace bytes.Buffer
+	writer := gzip.NewWriter(&gzip_trace)
+	writer.Write([]byte(compressed_text))
+	writer.Close()
+
+	traces := map[string][]byte{
+		"gzip":  gzip_trace.Bytes(),
+		"bzip2": bzip2_trace,
+		"plain": []byte(compressed_text),
+	}
+
+	for name, compressed := range traces {
+		reader, err := NewFormatReader(Twitter, bytes.NewReader(compressed))
+		if err != nil {
+			t.Errorf("%s: failed to create a reader: %v", name, err)
+			t.FailNow()
+		}
+
+		keys := []string{}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("%s: failed to read the trace: %v", name, err)
+				t.FailNow()
+			}
+			keys = append(keys, record.Key)
+		}
+
+		if strings.Join(keys, ",") != "a,b" {
+			t.Errorf("%s: expected keys a,b, got %v", name, keys)
+			t.FailNow()
+		}
+	}
+
+	// a trace that starts like gzip but has no valid header
+	if _, err := Decompress(bytes.NewReader([]byte{0x1f, 0x8b, 0x08, 0x00})); err == nil {
+		t.Errorf("Expected an error for a corrupt gzip header.")
+		t.FailNow()
+	}
+
+	// binary and text traces that only start like the magic numbers
+	for _, plain := range [][]byte{{0x1f, 0x8b, 0x00, 0x00}, []byte("BZh,a,1,9,2,get,0\n")} {
+		reader, err := Decompress(bytes.NewReader(plain))
+		if err != nil {
+			t.Errorf("Expected %q to be read as it is, got: %v", plain, err)
+			t.FailNow()
+		}
+
+		if read, _ := io.ReadAll(reader); !bytes.Equal(read, plain) {
+			t.Errorf("Expected %q to be read as it is, got %q", plain, read)
+			t.FailNow()
+		}
+	}
+}
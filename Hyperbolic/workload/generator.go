@@ -0,0 +1,337 @@
+// Package workload generates synthetic request streams, for when there
+// is no production trace to replay. A Generator picks the key of each
+// request, and a Reader turns its keys into trace Records that can be
+// replayed like any trace.
+package workload
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrInvalidParameter is returned when a Generator is created
+	// with a parameter it can not use.
+	ErrInvalidParameter = errors.New("invalid workload parameter")
+)
+
+// A Generator picks the key of each request in a workload.
+type Generator interface {
+
+	// Next returns the key of the next request.
+	Next() uint64
+}
+
+// A Zipf is a Generator that picks keys 0 through keys - 1 with
+// probability proportional to 1 / (rank + 1)^skew, so that key 0 is the
+// most popular. A skew of 0 is uniform, and larger skews concentrate
+// requests on fewer keys.
+type Zipf struct {
+
+	// source of randomness
+	random *rand.Rand
+
+	// probabilities of the keys, shared with other Zipfs
+	// over the same keys and skew
+	distribution *zipfDistribution
+}
+
+// NewZipf returns a Zipf over the given number of keys with the given
+// skew, seeded with seed. Unlike math/rand's Zipf, any skew of at least
+// 0 can be used, including the skews below 1 that most caching
+// workloads have. It holds a float64 per key once it picks its first key.
+func NewZipf(keys int, skew float64, seed int64) (*Zipf, error) {
+
+	distribution, err := newZipfDistribution(keys, skew)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Zipf{random: rand.New(rand.NewSource(seed)), distribution: distribution}, nil
+}
+
+// Next returns the key of the next request.
+func (zipf *Zipf) Next() uint64 {
+
+	cdf := zipf.distribution.get()
+
+	// find the first key whose cumulative probability covers the draw
+	draw := zipf.random.Float64()
+	key := sort.SearchFloat64s(cdf, draw)
+
+	// guard against rounding leaving the last cumulative probability below 1
+	if key == len(cdf) {
+		key--
+	}
+
+	return uint64(key)
+}
+
+// A zipfDistribution is the cumulative distribution of the keys of a
+// Zipf. It is only built once a Zipf picks its first key, and is never
+// changed after that, so the Zipfs of a Spec can share it across
+// goroutines.
+type zipfDistribution struct {
+
+	// number of keys
+	keys int
+
+	// skew of the distribution
+	skew float64
+
+	// makes sure cdf is only built once
+	once sync.Once
+
+	// cdf[i] is the probability that a key of at most i is picked
+	cdf []float64
+}
+
+// newZipfDistribution returns the unbuilt distribution of a Zipf over
+// the given number of keys with the given skew.
+func newZipfDistribution(keys int, skew float64) (*zipfDistribution, error) {
+
+	if keys <= 0 {
+		return nil, fmt.Errorf("%w: zipf keys %d must be positive", ErrInvalidParameter, keys)
+	}
+
+	if skew < 0 || math.IsNaN(skew) || math.IsInf(skew, 0) {
+		return nil, fmt.Errorf("%w: zipf skew %g must be at least 0", ErrInvalidParameter, skew)
+	}
+
+	return &zipfDistribution{keys: keys, skew: skew}, nil
+}
+
+// get returns the cumulative distribution, building it the first time.
+func (distribution *zipfDistribution) get() []float64 {
+
+	distribution.once.Do(func() {
+
+		// add up the weight of every key, then normalize
+		cdf := make([]float64, distribution.keys)
+		total := 0.0
+		for rank := range cdf {
+			total += 1 / math.Pow(float64(rank+1), distribution.skew)
+			cdf[rank] = total
+		}
+		for rank := range cdf {
+			cdf[rank] /= total
+		}
+
+		distribution.cdf = cdf
+	})
+
+	return distribution.cdf
+}
+
+// A Uniform is a Generator that picks keys 0 through keys - 1 with
+// equal probability.
+type Uniform struct {
+
+	// source of randomness
+	random *rand.Rand
+
+	// number of keys
+	keys int
+}
+
+// NewUniform returns a Uniform over the given number of keys, seeded with seed.
+func NewUniform(keys int, seed int64) (*Uniform, error) {
+
+	if keys <= 0 {
+		return nil, fmt.Errorf("%w: uniform keys %d must be positive", ErrInvalidParameter, keys)
+	}
+
+	return &Uniform{random: rand.New(rand.NewSource(seed)), keys: keys}, nil
+}
+
+// Next returns the key of the next request.
+func (uniform *Uniform) Next() uint64 {
+	return uint64(uniform.random.Intn(uniform.keys))
+}
+
+// A Scan is a Generator that requests keys in order, starting from a
+// given key, and never requests a key twice, like a sequential scan of
+// a table that does not fit in the cache.
+type Scan struct {
+
+	// key of the next request
+	next uint64
+}
+
+// NewScan returns a Scan that starts from the given key.
+func NewScan(start uint64) *Scan {
+	return &Scan{next: start}
+}
+
+// Next returns the key of the next request.
+func (scan *Scan) Next() uint64 {
+	key := scan.next
+	scan.next++
+	return key
+}
+
+// A Loop is a Generator that requests keys 0 through keys - 1 in order,
+// over and over, like a loop over a working set.
+type Loop struct {
+
+	// number of keys in the working set
+	keys uint64
+
+	// key of the next request
+	next uint64
+}
+
+// NewLoop returns a Loop over the given number of keys.
+func NewLoop(keys int) (*Loop, error) {
+
+	if keys <= 0 {
+		return nil, fmt.Errorf("%w: loop keys %d must be positive", ErrInvalidParameter, keys)
+	}
+
+	return &Loop{keys: uint64(keys)}, nil
+}
+
+// Next returns the key of the next request.
+func (loop *Loop) Next() uint64 {
+	key := loop.next
+	loop.next = (loop.next + 1) % loop.keys
+	return key
+}
+
+// A HotSet is a Generator whose requests mostly go to a small set of hot
+// keys that shifts over time. A fraction of requests pick uniformly from
+// the hot keys, and the rest pick uniformly from every key. Every period
+// requests, the hot keys move on to the next hot_keys keys.
+type HotSet struct {
+
+	// source of randomness
+	random *rand.Rand
+
+	// number of keys
+	keys int
+
+	// number of hot keys
+	hot_keys int
+
+	// fraction of requests that go to the hot keys
+	hot_fraction float64
+
+	// number of requests after which the hot keys shift
+	period int
+
+	// number of requests so far
+	requests int
+}
+
+// NewHotSet returns a HotSet over the given number of keys, with the
+// given number of hot keys that get hot_fraction of requests and shift
+// every period requests, seeded with seed.
+func NewHotSet(keys int, hot_keys int, hot_fraction float64, period int, seed int64) (*HotSet, error) {
+
+	if keys <= 0 {
+		return nil, fmt.Errorf("%w: hot set keys %d must be positive", ErrInvalidParameter, keys)
+	}
+
+	if hot_keys <= 0 || hot_keys > keys {
+		return nil, fmt.Errorf("%w: hot keys %d must be positive and at most keys %d",
+			ErrInvalidParameter, hot_keys, keys)
+	}
+
+	if hot_fraction < 0 || hot_fraction > 1 || math.IsNaN(hot_fraction) {
+		return nil, fmt.Errorf("%w: hot fraction %g must be between 0 and 1",
+			ErrInvalidParameter, hot_fraction)
+	}
+
+	if period <= 0 {
+		return nil, fmt.Errorf("%w: hot set period %d must be positive", ErrInvalidParameter, period)
+	}
+
+	return &HotSet{random: rand.New(rand.NewSource(seed)), keys: keys, hot_keys: hot_keys,
+		hot_fraction: hot_fraction, period: period}, nil
+}
+
+// Next returns the key of the next request.
+func (hot_set *HotSet) Next() uint64 {
+
+	// the hot keys start after every hot set before them, wrapping around
+	shifts := hot_set.requests / hot_set.period
+	hot_set.requests++
+
+	if hot_set.random.Float64() >= hot_set.hot_fraction {
+		return uint64(hot_set.random.Intn(hot_set.keys))
+	}
+
+	first := (shifts * hot_set.hot_keys) % hot_set.keys
+	return uint64((first + hot_set.random.Intn(hot_set.hot_keys)) % hot_set.keys)
+}
+
+// A Component is a Generator in a Mixture, with its weight.
+type Component struct {
+
+	// the Generator
+	Generator Generator
+
+	// how often the Generator is picked, relative to
+	// the other Components of the Mixture
+	Weight float64
+}
+
+// A Mixture is a Generator that picks each request from one of its
+// Components, with probability proportional to the Component's weight.
+// Components draw from the same keys, so Components that should not
+// share keys should be given keys that do not overlap, such as a Scan
+// that starts past the keys of a Zipf.
+type Mixture struct {
+
+	// source of randomness
+	random *rand.Rand
+
+	// the Components
+	components []Component
+
+	// cdf[i] is the probability that a Component of at most i is picked
+	cdf []float64
+}
+
+// NewMixture returns a Mixture of the given Components, seeded with seed.
+func NewMixture(components []Component, seed int64) (*Mixture, error) {
+
+	if len(components) == 0 {
+		return nil, fmt.Errorf("%w: mixture has no components", ErrInvalidParameter)
+	}
+
+	cdf := make([]float64, len(components))
+	total := 0.0
+	for i, component := range components {
+		if component.Generator == nil {
+			return nil, fmt.Errorf("%w: mixture component %d has no generator", ErrInvalidParameter, i)
+		}
+		if component.Weight <= 0 || math.IsNaN(component.Weight) || math.IsInf(component.Weight, 0) {
+			return nil, fmt.Errorf("%w: mixture component %d weight %g must be positive",
+				ErrInvalidParameter, i, component.Weight)
+		}
+		total += component.Weight
+		cdf[i] = total
+	}
+	for i := range cdf {
+		cdf[i] /= total
+	}
+
+	return &Mixture{random: rand.New(rand.NewSource(seed)), components: components, cdf: cdf}, nil
+}
+
+// Next returns the key of the next request.
+func (mixture *Mixture) Next() uint64 {
+
+	draw := mixture.random.Float64()
+	i := sort.SearchFloat64s(mixture.cdf, draw)
+	if i == len(mixture.cdf) {
+		i--
+	}
+
+	return mixture.components[i].Generator.Next()
+}
@@ -0,0 +1,49 @@
+package workload
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/jimmytienhoangy/COS316_Project/trace"
+)
+
+// A Reader turns the keys a Generator picks into trace Records, so a
+// workload can be replayed like a trace. Every Record is a Get of an
+// item of the same size, timestamped by its position in the workload.
+type Reader struct {
+
+	// picks the key of each request
+	generator Generator
+
+	// number of requests in the workload
+	requests int
+
+	// number of requests read so far
+	read int
+
+	// size of every requested item
+	value_size int
+}
+
+// NewReader returns a Reader of the given number of requests, with keys
+// picked by the generator, for items of value_size bytes.
+func NewReader(generator Generator, requests int, value_size int) *Reader {
+	return &Reader{generator: generator, requests: requests, value_size: value_size}
+}
+
+// Read returns the next Record in the workload, or io.EOF once every
+// request has been read.
+func (reader *Reader) Read() (trace.Record, error) {
+
+	if reader.read >= reader.requests {
+		return trace.Record{}, io.EOF
+	}
+	reader.read++
+
+	return trace.Record{
+		Timestamp: reader.read,
+		Key:       strconv.FormatUint(reader.generator.Next(), 10),
+		ValueSize: reader.value_size,
+		Operation: trace.Get,
+	}, nil
+}
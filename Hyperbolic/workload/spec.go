@@ -0,0 +1,170 @@
+package workload
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// A Spec describes a workload as text, so that workloads can be given on
+// the command line. A Spec is one or more Generators separated by "|",
+// which make up a Mixture if there is more than one. Each Generator is
+// written as its name, optionally followed by ":" and comma-separated
+// parameters:
+//
+//	zipf:keys=10000,skew=0.8
+//	uniform:keys=10000
+//	scan:start=1000000
+//	loop:keys=500
+//	hotset:keys=10000,hot=100,fraction=0.9,period=5000
+//	zipf:keys=10000,weight=3|scan:start=10000,weight=1
+//
+// Parameters that are left out take the defaults in spec_defaults.
+type Spec struct {
+
+	// the text the Spec was parsed from
+	text string
+
+	// the Generators that make up the workload
+	components []component_spec
+}
+
+// component_spec describes a single Generator of a Spec.
+type component_spec struct {
+
+	// name of the Generator
+	name string
+
+	// the Generator's parameters, by name
+	parameters map[string]float64
+
+	// distribution of a zipf Generator's keys, shared by every
+	// Generator the Spec creates, so that it is only built once
+	zipf *zipfDistribution
+}
+
+// spec_defaults holds the parameters each Generator takes and their defaults.
+var spec_defaults = map[string]map[string]float64{
+	"zipf":    {"keys": 10000, "skew": 0.99, "weight": 1},
+	"uniform": {"keys": 10000, "weight": 1},
+	"scan":    {"start": 0, "weight": 1},
+	"loop":    {"keys": 10000, "weight": 1},
+	"hotset":  {"keys": 10000, "hot": 100, "fraction": 0.9, "period": 10000, "weight": 1},
+}
+
+// spec_integers holds the parameters that count something, and so must be whole numbers.
+var spec_integers = map[string]bool{"keys": true, "hot": true, "period": true, "start": true}
+
+// Parse parses the text of a Spec, returning an error if it names an
+// unknown Generator or parameter or a parameter can not be used.
+func Parse(text string) (Spec, error) {
+
+	spec := Spec{text: text}
+
+	for _, component := range strings.Split(text, "|") {
+		name, parameters, _ := strings.Cut(strings.TrimSpace(component), ":")
+
+		defaults, ok := spec_defaults[name]
+		if !ok {
+			return spec, fmt.Errorf("%w: unknown generator %q", ErrInvalidParameter, name)
+		}
+
+		parsed := component_spec{name: name, parameters: map[string]float64{}}
+		for parameter, value := range defaults {
+			parsed.parameters[parameter] = value
+		}
+
+		for _, parameter := range strings.Split(parameters, ",") {
+			if parameter == "" {
+				continue
+			}
+
+			key, value, _ := strings.Cut(parameter, "=")
+			if _, ok := defaults[key]; !ok {
+				return spec, fmt.Errorf("%w: %s has no parameter %q", ErrInvalidParameter, name, key)
+			}
+
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return spec, fmt.Errorf("%w: %s %s: %w", ErrInvalidParameter, name, key, err)
+			}
+			if spec_integers[key] && number != math.Trunc(number) {
+				return spec, fmt.Errorf("%w: %s %s %g must be a whole number", ErrInvalidParameter, name, key, number)
+			}
+			parsed.parameters[key] = number
+		}
+
+		if name == "zipf" {
+			distribution, err := newZipfDistribution(int(parsed.parameters["keys"]), parsed.parameters["skew"])
+			if err != nil {
+				return spec, err
+			}
+			parsed.zipf = distribution
+		}
+
+		spec.components = append(spec.components, parsed)
+	}
+
+	// create the generators once to check their parameters, which
+	// is cheap since zipf distributions are only built when used
+	if _, err := spec.New(0); err != nil {
+		return spec, err
+	}
+
+	return spec, nil
+}
+
+// String returns the text the Spec was parsed from.
+func (spec Spec) String() string {
+	return spec.text
+}
+
+// New creates the Generator the Spec describes, seeded with seed. Specs
+// create the same requests every time they are given the same seed.
+func (spec Spec) New(seed int64) (Generator, error) {
+
+	components := make([]Component, len(spec.components))
+
+	for i, component := range spec.components {
+
+		// give every component its own seed
+		component_seed := seed + int64(i)
+		parameter := component.parameters
+
+		var generator Generator
+		var err error
+
+		switch component.name {
+		case "zipf":
+			generator = &Zipf{random: rand.New(rand.NewSource(component_seed)), distribution: component.zipf}
+		case "uniform":
+			generator, err = NewUniform(int(parameter["keys"]), component_seed)
+		case "scan":
+			if parameter["start"] < 0 {
+				err = fmt.Errorf("%w: scan start %g must be at least 0", ErrInvalidParameter, parameter["start"])
+			}
+			generator = NewScan(uint64(parameter["start"]))
+		case "loop":
+			generator, err = NewLoop(int(parameter["keys"]))
+		case "hotset":
+			generator, err = NewHotSet(int(parameter["keys"]), int(parameter["hot"]),
+				parameter["fraction"], int(parameter["period"]), component_seed)
+		default:
+			err = fmt.Errorf("%w: unknown generator %q", ErrInvalidParameter, component.name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		components[i] = Component{Generator: generator, Weight: parameter["weight"]}
+	}
+
+	if len(components) == 1 {
+		return components[0].Generator, nil
+	}
+
+	return NewMixture(components, seed+int64(len(components)))
+}
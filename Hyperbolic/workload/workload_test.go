@@ -0,0 +1,220 @@
+package workload
+
+import (
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+// draw returns how many times each key was picked in the given number of requests.
+func draw(generator Generator, requests int) map[uint64]int {
+	counts := map[uint64]int{}
+	for i := 0; i < requests; i++ {
+		counts[generator.Next()]++
+	}
+	return counts
+}
+
+// Tests that a Zipf picks keys with the probabilities its skew gives them,
+// including skews below 1.
+func Test_Zipf(t *testing.T) {
+	for _, skew := range []float64{0, 0.7, 1.2} {
+		zipf, err := NewZipf(100, skew, 316)
+		if err != nil {
+			t.Errorf("Failed to create zipf with skew %g: %v", skew, err)
+			t.FailNow()
+		}
+
+		total := 0.0
+		for rank := 1; rank <= 100; rank++ {
+			total += 1 / math.Pow(float64(rank), skew)
+		}
+
+		counts := draw(zipf, 200000)
+		for _, key := range []uint64{0, 1, 9} {
+			expected := 1 / math.Pow(float64(key+1), skew) / total
+			actual := float64(counts[key]) / 200000
+			if math.Abs(actual-expected) > 0.01 {
+				t.Errorf("skew %g: expected key %d to be picked %.3f of the time, got %.3f",
+					skew, key, expected, actual)
+				t.FailNow()
+			}
+		}
+
+		for key := range counts {
+			if key >= 100 {
+				t.Errorf("skew %g: picked key %d out of range", skew, key)
+				t.FailNow()
+			}
+		}
+	}
+
+	if _, err := NewZipf(0, 1, 0); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected an invalid parameter error, got: %v", err)
+		t.FailNow()
+	}
+
+	if _, err := NewZipf(10, -1, 0); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected an invalid parameter error, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// Tests that generators with the same seed pick the same keys.
+func Test_Seeded(t *testing.T) {
+	spec, err := Parse("zipf:keys=1000,skew=0.9|uniform:keys=50|hotset:keys=500,hot=10,period=30")
+	if err != nil {
+		t.Errorf("Failed to parse spec: %v", err)
+		t.FailNow()
+	}
+
+	first, _ := spec.New(42)
+	second, _ := spec.New(42)
+	other, _ := spec.New(43)
+
+	different := false
+	for i := 0; i < 1000; i++ {
+		key := first.Next()
+		if key != second.Next() {
+			t.Errorf("Expected generators with the same seed to pick the same keys.")
+			t.FailNow()
+		}
+		if key != other.Next() {
+			different = true
+		}
+	}
+
+	if !different {
+		t.Errorf("Expected generators with different seeds to pick different keys.")
+		t.FailNow()
+	}
+}
+
+// Tests that scans never repeat keys and loops cycle through their keys.
+func Test_ScanLoop(t *testing.T) {
+	scan := NewScan(10)
+	for i := uint64(10); i < 20; i++ {
+		if key := scan.Next(); key != i {
+			t.Errorf("Expected scan to pick key %d, got %d", i, key)
+			t.FailNow()
+		}
+	}
+
+	loop, _ := NewLoop(3)
+	for i := uint64(0); i < 9; i++ {
+		if key := loop.Next(); key != i%3 {
+			t.Errorf("Expected loop to pick key %d, got %d", i%3, key)
+			t.FailNow()
+		}
+	}
+
+	if _, err := NewLoop(0); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected an invalid parameter error, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// Tests that the hot keys of a hot set shift every period.
+func Test_HotSet(t *testing.T) {
+	hot_set, err := NewHotSet(100, 10, 1, 50, 316)
+	if err != nil {
+		t.Errorf("Failed to create hot set: %v", err)
+		t.FailNow()
+	}
+
+	for period := uint64(0); period < 12; period++ {
+		first := (period * 10) % 100
+		for i := 0; i < 50; i++ {
+			if key := hot_set.Next(); key < first || key >= first+10 {
+				t.Errorf("Expected period %d to pick keys %d through %d, got %d",
+					period, first, first+9, key)
+				t.FailNow()
+			}
+		}
+	}
+
+	if _, err := NewHotSet(100, 101, 0.5, 10, 0); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected an invalid parameter error, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// Tests that a mixture picks its components in proportion to their weights.
+func Test_Mixture(t *testing.T) {
+	loop, _ := NewLoop(1)
+	mixture, err := NewMixture([]Component{
+		{Generator: loop, Weight: 3},
+		{Generator: NewScan(1), Weight: 1},
+	}, 316)
+	if err != nil {
+		t.Errorf("Failed to create mixture: %v", err)
+		t.FailNow()
+	}
+
+	counts := draw(mixture, 100000)
+	if fraction := float64(counts[0]) / 100000; math.Abs(fraction-0.75) > 0.01 {
+		t.Errorf("Expected the loop to be picked 0.75 of the time, got %.3f", fraction)
+		t.FailNow()
+	}
+
+	if _, err := NewMixture([]Component{{Generator: loop, Weight: 0}}, 0); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected an invalid parameter error, got: %v", err)
+		t.FailNow()
+	}
+}
+
+// Tests that specs with unknown generators or parameters, or unusable
+// parameters, are rejected.
+func Test_ParseInvalid(t *testing.T) {
+	for _, text := range []string{"", "normal", "zipf:size=10", "zipf:keys=ten",
+		"zipf:keys=0", "scan:start=-1", "loop|uniform:weight=-1", "loop:keys=1.5",
+		"hotset:period=2.9", "scan:start=0.5"} {
+		if _, err := Parse(text); !errors.Is(err, ErrInvalidParameter) {
+			t.Errorf("Expected spec %q to be rejected, got: %v", text, err)
+			t.FailNow()
+		}
+	}
+}
+
+// Tests that parsing a zipf spec does not build its distribution, and
+// that the generators of a spec share it once it is built.
+func Test_ParseZipfShared(t *testing.T) {
+
+	// a billion keys would take 8GB to build
+	spec, err := Parse("zipf:keys=1000000000|scan")
+	if err != nil || spec.components[0].zipf.cdf != nil {
+		t.Errorf("Expected an unbuilt zipf distribution, got: %v", err)
+		t.FailNow()
+	}
+
+	spec, _ = Parse("zipf:keys=100")
+	first, _ := spec.New(1)
+	second, _ := spec.New(2)
+	first.Next()
+	second.Next()
+
+	if first.(*Zipf).distribution != second.(*Zipf).distribution || len(spec.components[0].zipf.cdf) != 100 {
+		t.Errorf("Expected the generators of a spec to share one built distribution")
+		t.FailNow()
+	}
+}
+
+// Tests that a Reader reads the given number of requests as Gets.
+func Test_Reader(t *testing.T) {
+	loop, _ := NewLoop(2)
+	reader := NewReader(loop, 3, 8)
+
+	for i, key := range []string{"0", "1", "0"} {
+		record, err := reader.Read()
+		if err != nil || record.Key != key || record.Timestamp != i+1 || record.ValueSize != 8 {
+			t.Errorf("Expected request %d for key %s, got %+v (%v)", i+1, key, record, err)
+			t.FailNow()
+		}
+	}
+
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got: %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,414 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// percentage of a WTinyLFUCache's max capacity used by its window
+const wtinylfu_window_percent = 1
+
+// percentage of a WTinyLFUCache's main region used by its protected segment
+const wtinylfu_protected_percent = 80
+
+// A WTinyLFUCache is a fixed-size, in-memory cache with W-TinyLFU
+// eviction. New items enter a small LRU window. Items pushed out of the
+// window are candidates for the main region, a segmented LRU of a
+// probation and a protected segment, and are only admitted if a
+// count-min sketch estimates that they were accessed more often than
+// the item they would evict. Items hit in probation move to protected.
+type WTinyLFUCache[K comparable, V any] struct {
+
+	// total number of items (or bytes, in byte capacity
+	// mode) the WTinyLFUCache can store
+	max_capacity int
+
+	// whether max_capacity counts bytes instead of items
+	by_bytes bool
+
+	// how much of max_capacity the window can use
+	window_capacity int
+
+	// how much of max_capacity the protected segment can use
+	protected_capacity int
+
+	// items recently set, evicted to probation
+	window segment
+
+	// items admitted to the main region, or hit there once
+	// they were demoted from protected
+	probation segment
+
+	// items hit while in probation
+	protected segment
+
+	// mapping of keys to items in any of the segments
+	keys_to_items map[K]*list.Element
+
+	// estimates of how often keys were recently accessed
+	sketch *frequencySketch
+
+	// number of hits from the WTinyLFUCache
+	hits int
+
+	// number of misses from the WTinyLFUCache
+	misses int
+
+	// total size of the items hit in the WTinyLFUCache
+	hit_bytes int
+
+	// total size of the items missed in the WTinyLFUCache
+	miss_bytes int
+
+	// number of items evicted from the WTinyLFUCache
+	evictions int
+
+	// clock that tells the WTinyLFUCache when items expire
+	clock Clock
+}
+
+// A WTinyLFUCacheItem holds a key, value pair to be put in one of
+// the segments of a WTinyLFUCache.
+type WTinyLFUCacheItem[K comparable, V any] struct {
+	key        K
+	value      V
+	size       int
+	expires_at int
+
+	// hash of the key in the sketch
+	hash uint64
+
+	// segment the item is in
+	location *segment
+}
+
+// NewWTinyLFUCache returns a pointer to a new, empty WTinyLFUCache.
+func NewWTinyLFUCache[K comparable, V any](max_capacity int, opts ...Option) (*WTinyLFUCache[K, V], error) {
+
+	if err := validateCapacity(max_capacity); err != nil {
+		return nil, err
+	}
+
+	config := newOptions(opts)
+
+	// the window holds at least one item
+	window_capacity := min(max(max_capacity*wtinylfu_window_percent/100, 1), max_capacity)
+
+	// a max capacity of bytes says little about how many items the
+	// cache holds, so in byte capacity mode the sketch starts small
+	// and grows with the number of items instead
+	sketch_keys := max_capacity
+	if config.by_bytes {
+		sketch_keys = 1
+	}
+
+	// create and initialize a new WTinyLFUCache
+	return &WTinyLFUCache[K, V]{
+		max_capacity:       max_capacity,
+		by_bytes:           config.by_bytes,
+		clock:              config.clock,
+		window_capacity:    window_capacity,
+		protected_capacity: (max_capacity - window_capacity) * wtinylfu_protected_percent / 100,
+		window:             newSegment(),
+		probation:          newSegment(),
+		protected:          newSegment(),
+		keys_to_items:      make(map[K]*list.Element),
+		sketch:             newFrequencySketch(sketch_keys),
+		hits:               0,
+		misses:             0,
+	}, nil
+}
+
+// Get returns the value of the item with the key and a success boolean
+// indicating if the item was found.
+// This operation counts as a "use" for that item.
+func (wtl *WTinyLFUCache[K, V]) Get(key K, opts ...ItemOption) (value V, success bool) {
+
+	// read the time of this request
+	now := wtl.clock.Now()
+
+	// check if there is an item with the given key
+	existing_item, ok := wtl.keys_to_items[key]
+
+	// an expired item is removed and counts as a miss
+	if ok && expired(existing_item.Value.(*WTinyLFUCacheItem[K, V]).expires_at, now) {
+		wtl.remove(existing_item)
+		ok = false
+	}
+
+	if !ok {
+		wtl.misses++
+		wtl.miss_bytes += requestSize(opts)
+		return value, false
+	}
+
+	wtl.hits++
+
+	existing_item = wtl.use(existing_item)
+
+	item := existing_item.Value.(*WTinyLFUCacheItem[K, V])
+	wtl.hit_bytes += item.size
+
+	return item.value, true
+}
+
+// Set sets the value of the item with the given key, possibly evicting
+// items to make room for it. New items are set in the window, and an
+// item too large for the window is evicted at once if it loses
+// admission to the main region.
+// This operation counts as a "use" for that item.
+// Returns true if the item was added/updated successfully, else false.
+func (wtl *WTinyLFUCache[K, V]) Set(key K, value V, opts ...ItemOption) (success bool, err error) {
+
+	// read the time of this request
+	operation_timestamp := wtl.clock.Now()
+
+	description, err := newItemOptions(opts)
+	if err != nil {
+		return false, err
+	}
+
+	// can not set if cache max capacity is 0!
+	if wtl.max_capacity == 0 {
+		return false, nil
+	}
+
+	item_weight := weight(wtl.by_bytes, description.size)
+
+	// check if there is an existing item with the key
+	existing_item, ok := wtl.keys_to_items[key]
+
+	// an item larger than the whole cache can never fit,
+	// and any older value for its key is now stale
+	if item_weight > wtl.max_capacity {
+		if ok {
+			wtl.remove(existing_item)
+		}
+		return false, nil
+	}
+
+	if ok {
+		existing_item = wtl.use(existing_item)
+
+		item := existing_item.Value.(*WTinyLFUCacheItem[K, V])
+		item.value = value
+		item.expires_at = expiry(operation_timestamp, description.ttl)
+
+		// account for a change in size, evicting other
+		// items if the item no longer fits
+		item.location.used += item_weight - weight(wtl.by_bytes, item.size)
+		item.size = description.size
+		wtl.makeRoom(existing_item)
+
+		return true, nil
+	}
+
+	// count the access of the new key, so that it can win
+	// admission to the main region if it is set again
+	if wtl.by_bytes {
+		wtl.sketch.grow(len(wtl.keys_to_items) + 1)
+	}
+	hash := keyHash(key)
+	wtl.sketch.increment(hash)
+
+	// insert the item into the window
+	item := &WTinyLFUCacheItem[K, V]{key: key, value: value, size: description.size,
+		expires_at: expiry(operation_timestamp, description.ttl), hash: hash, location: &wtl.window}
+	wtl.keys_to_items[key] = wtl.window.linked_list.PushBack(item)
+	wtl.window.used += item_weight
+
+	wtl.makeRoom(nil)
+
+	return true, nil
+}
+
+// use counts an access of an item in the sketch and moves it to the most
+// recently used end of its segment, or from probation to protected,
+// demoting the least recently used items of protected to probation if
+// it is full. It returns the item's new element.
+func (wtl *WTinyLFUCache[K, V]) use(element *list.Element) *list.Element {
+
+	item := element.Value.(*WTinyLFUCacheItem[K, V])
+	wtl.sketch.increment(item.hash)
+
+	if item.location != &wtl.probation {
+		item.location.linked_list.MoveToBack(element)
+		return element
+	}
+
+	element = wtl.move(element, &wtl.protected)
+
+	for wtl.protected.used > wtl.protected_capacity {
+		demoted := leastRecent(&wtl.protected, element)
+		if demoted == nil {
+			break
+		}
+		wtl.move(demoted, &wtl.probation)
+	}
+
+	return element
+}
+
+// makeRoom moves the least recently used items of the window to
+// probation while the window is over its capacity, and then evicts
+// items until the WTinyLFUCache is no longer over its max capacity.
+// Each item moved from the window is a candidate that is compared with
+// the least recently used item of the main region: the candidate is
+// admitted if it was accessed more often, and evicted otherwise. Once
+// neither the window nor the main region has a candidate or victim
+// left, items are evicted from the window. The protected item is never
+// evicted.
+func (wtl *WTinyLFUCache[K, V]) makeRoom(protected *list.Element) {
+
+	candidates := []*list.Element{}
+
+	for wtl.window.used > wtl.window_capacity {
+		candidates = append(candidates, wtl.move(wtl.window.linked_list.Front(), &wtl.probation))
+	}
+
+	// the protected item has a new element if it left the window
+	if protected != nil {
+		protected = wtl.keys_to_items[protected.Value.(*WTinyLFUCacheItem[K, V]).key]
+	}
+
+	for wtl.window.used+wtl.probation.used+wtl.protected.used > wtl.max_capacity {
+
+		var candidate *list.Element
+		if len(candidates) > 0 {
+			candidate = candidates[0]
+		}
+
+		// candidates are behind every other item in probation,
+		// so look in protected if probation has none of those
+		victim := leastRecent(&wtl.probation, protected)
+		if victim == candidate {
+			victim = leastRecent(&wtl.protected, protected)
+		}
+
+		switch {
+
+		// an item of the main region grew and is the only item
+		// left there, so make room for it in the window instead
+		case candidate == nil && victim == nil:
+			victim = leastRecent(&wtl.window, protected)
+			if victim == nil {
+				return
+			}
+			wtl.evict(victim)
+
+		case candidate == nil:
+			wtl.evict(victim)
+
+		// the main region only holds other candidates,
+		// so the candidate can not be compared with any item
+		case victim == nil:
+			if candidate != protected {
+				wtl.evict(candidate)
+			}
+			candidates = candidates[1:]
+
+		// an admitted candidate keeps being compared with victims
+		// until the WTinyLFUCache is no longer over its max capacity
+		case candidate == protected || wtl.admit(candidate, victim):
+			wtl.evict(victim)
+
+		default:
+			wtl.evict(candidate)
+			candidates = candidates[1:]
+		}
+	}
+}
+
+// admit returns true if the sketch estimates that the candidate was
+// accessed more often than the victim.
+func (wtl *WTinyLFUCache[K, V]) admit(candidate *list.Element, victim *list.Element) bool {
+	return wtl.sketch.estimate(candidate.Value.(*WTinyLFUCacheItem[K, V]).hash) >
+		wtl.sketch.estimate(victim.Value.(*WTinyLFUCacheItem[K, V]).hash)
+}
+
+// Delete removes the item with the given key from the WTinyLFUCache.
+// Returns true if the item was found and removed, else false.
+func (wtl *WTinyLFUCache[K, V]) Delete(key K) (success bool) {
+
+	// check if there is an item with the given key
+	existing_item, ok := wtl.keys_to_items[key]
+	if !ok {
+		return false
+	}
+
+	wtl.remove(existing_item)
+
+	return true
+}
+
+// move moves an item to the most recently used end of the given
+// segment and returns its new element.
+func (wtl *WTinyLFUCache[K, V]) move(element *list.Element, location *segment) *list.Element {
+
+	item := element.Value.(*WTinyLFUCacheItem[K, V])
+	item_weight := weight(wtl.by_bytes, item.size)
+
+	item.location.linked_list.Remove(element)
+	item.location.used -= item_weight
+
+	item.location = location
+	location.used += item_weight
+
+	moved := location.linked_list.PushBack(item)
+	wtl.keys_to_items[item.key] = moved
+
+	return moved
+}
+
+// evict removes an item to make room for other items.
+func (wtl *WTinyLFUCache[K, V]) evict(element *list.Element) {
+	wtl.remove(element)
+	wtl.evictions++
+}
+
+// remove unlinks an item from its segment and the map.
+func (wtl *WTinyLFUCache[K, V]) remove(element *list.Element) {
+
+	item := element.Value.(*WTinyLFUCacheItem[K, V])
+
+	item.location.linked_list.Remove(element)
+	item.location.used -= weight(wtl.by_bytes, item.size)
+	delete(wtl.keys_to_items, item.key)
+}
+
+// Contains returns true if an item with the key is in the WTinyLFUCache,
+// whether or not it has expired, without counting as a use.
+func (wtl *WTinyLFUCache[K, V]) Contains(key K) bool {
+	_, ok := wtl.keys_to_items[key]
+	return ok
+}
+
+// Victim returns the key of the item the WTinyLFUCache would evict first to
+// make room for a new item of the given size, and true, or false if
+// the item fits without evicting anything.
+// The victim is the least recently used item of the main region,
+// which a new item pushed out of the window has to win admission over.
+func (wtl *WTinyLFUCache[K, V]) Victim(size int) (key K, ok bool) {
+
+	used := wtl.window.used + wtl.probation.used + wtl.protected.used
+	if used+weight(wtl.by_bytes, size) <= wtl.max_capacity {
+		return key, false
+	}
+
+	victim := leastRecent(&wtl.probation, nil)
+	if victim == nil {
+		victim = leastRecent(&wtl.protected, nil)
+	}
+
+	if victim == nil {
+		return key, false
+	}
+
+	return victim.Value.(*WTinyLFUCacheItem[K, V]).key, true
+}
+
+// Stats returns statistics about how many search hits and misses have
+// occurred in the WTinyLFUCache.
+func (wtl *WTinyLFUCache[K, V]) Stats() *Stats {
+	return &Stats{Hits: wtl.hits, Misses: wtl.misses,
+		HitBytes: wtl.hit_bytes, MissBytes: wtl.miss_bytes, Evictions: wtl.evictions}
+}